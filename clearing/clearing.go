@@ -0,0 +1,243 @@
+// Package clearing checks decrypted clearing prices against the bid they
+// were charged for, pricers having no auction context.
+package clearing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/benjaminch/pricers"
+)
+
+// ErrAnomaly is matched by the errors of rejected clearing prices.
+var ErrAnomaly = errors.New("Clearing price is anomalous")
+
+// AuctionType : OpenRTB auction type, the `at` field of bid requests.
+type AuctionType int
+
+const (
+	// UnknownAuction : Auction type is unknown, checks depending on it being skipped.
+	UnknownAuction AuctionType = 0
+	// FirstPrice : Winners pay their bid.
+	FirstPrice AuctionType = 1
+	// SecondPrice : Winners pay the second highest bid, or the floor, plus an increment.
+	SecondPrice AuctionType = 2
+)
+
+// String : Returns the AuctionType string representation.
+func (t AuctionType) String() string {
+	switch t {
+	case FirstPrice:
+		return "first_price"
+	case SecondPrice:
+		return "second_price"
+	}
+	return "unknown"
+}
+
+// Bid holds what was submitted for the auction a clearing price was charged for.
+type Bid struct {
+	// Price is the bid price, zero if unknown.
+	Price float64
+	// AuctionType is the auction type of the bid request.
+	AuctionType AuctionType
+	// Floor is the bid floor of the impression, zero if none.
+	Floor float64
+}
+
+// Anomaly : Describing how a clearing price disagrees with its bid.
+type Anomaly int
+
+const (
+	// AboveBid : Price is above the bid, which is either a bug or fraud.
+	AboveBid Anomaly = iota
+	// BelowFloor : Price is below the floor.
+	BelowFloor
+	// ExactBidSecondPrice : Price is exactly the bid in a second price auction,
+	// which happens when the second bid ties, but also when the exchange
+	// runs a first price auction.
+	ExactBidSecondPrice
+
+	numAnomalies = iota
+)
+
+// String : Returns the Anomaly string representation.
+func (a Anomaly) String() string {
+	switch a {
+	case AboveBid:
+		return "above_bid"
+	case BelowFloor:
+		return "below_floor"
+	case ExactBidSecondPrice:
+		return "exact_bid_second_price"
+	}
+	return "unknown"
+}
+
+// Policy : Describing how anomalous clearing prices should be handled.
+type Policy int
+
+const (
+	// Flag : Anomalous prices are returned, their anomalies being reported in the Result. It is the default.
+	Flag Policy = iota
+	// Reject : Anomalous prices fail with an *AnomalyError.
+	Reject
+)
+
+// Result holds a checked clearing price.
+type Result struct {
+	Price float64
+	// Anomalies are the ways the price disagrees with its bid, if any.
+	Anomalies []Anomaly
+}
+
+// Flagged : Tells whether the price has anomalies.
+func (r Result) Flagged() bool {
+	return len(r.Anomalies) > 0
+}
+
+// AnomalyError is returned for rejected clearing prices. It matches ErrAnomaly.
+type AnomalyError struct {
+	Result
+	Bid Bid
+}
+
+func (e *AnomalyError) Error() string {
+	anomalies := make([]string, len(e.Anomalies))
+	for i, anomaly := range e.Anomalies {
+		anomalies[i] = anomaly.String()
+	}
+	return fmt.Sprintf("clearing: price %g for a %g %s bid with a %g floor is anomalous: %s",
+		e.Price, e.Bid.Price, e.Bid.AuctionType, e.Bid.Floor, strings.Join(anomalies, ", "))
+}
+
+// Unwrap : Returns ErrAnomaly.
+func (e *AnomalyError) Unwrap() error {
+	return ErrAnomaly
+}
+
+// Event describes an anomalous clearing price.
+type Event struct {
+	Anomaly Anomaly
+	Price   float64
+	Bid     Bid
+	// Rejected tells whether the price was rejected, or flagged.
+	Rejected bool
+}
+
+// Observer is notified of every anomaly.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	Observe(event Event)
+}
+
+// ObserverFunc is an adapter allowing a function to be used as an Observer.
+type ObserverFunc func(event Event)
+
+// Observe : Calls f(event).
+func (f ObserverFunc) Observe(event Event) {
+	f(event)
+}
+
+// DefaultTolerance is the default tolerance of price comparisons, one
+// micro, the precision of prices scaled by a factor of 1,000,000.
+const DefaultTolerance = 1e-6
+
+// Validator decrypts clearing prices and checks them against their bid.
+// A Validator is safe for concurrent use.
+type Validator struct {
+	pricer    pricers.Decrypter
+	policies  [numAnomalies]Policy
+	tolerance float64
+	observer  Observer
+}
+
+// Option is a Validator option.
+type Option func(*Validator)
+
+// WithPolicy sets the policy of every anomaly, Flag by default.
+func WithPolicy(policy Policy) Option {
+	return func(v *Validator) {
+		for i := range v.policies {
+			v.policies[i] = policy
+		}
+	}
+}
+
+// WithAnomalyPolicy sets the policy of an anomaly, overriding WithPolicy if given after it.
+func WithAnomalyPolicy(anomaly Anomaly, policy Policy) Option {
+	return func(v *Validator) {
+		if anomaly >= 0 && anomaly < numAnomalies {
+			v.policies[anomaly] = policy
+		}
+	}
+}
+
+// WithTolerance sets the tolerance of price comparisons, DefaultTolerance by default.
+func WithTolerance(tolerance float64) Option {
+	return func(v *Validator) {
+		v.tolerance = math.Abs(tolerance)
+	}
+}
+
+// WithObserver sets the observer notified of every anomaly.
+func WithObserver(observer Observer) Option {
+	return func(v *Validator) {
+		v.observer = observer
+	}
+}
+
+// NewValidator returns a Validator decrypting clearing prices with pricer,
+// e.g. a DoubleClickPricer or a doubleclick.Router seat.
+func NewValidator(pricer pricers.Decrypter, options ...Option) *Validator {
+	v := &Validator{pricer: pricer, tolerance: DefaultTolerance}
+	for _, option := range options {
+		option(v)
+	}
+	return v
+}
+
+// Decrypt decrypts a clearing price and checks it against its bid, see Check.
+// Decryption errors are returned as is.
+func (v *Validator) Decrypt(encryptedPrice string, bid Bid) (Result, error) {
+	price, err := v.pricer.Decrypt(encryptedPrice)
+	if err != nil {
+		return Result{Price: price}, err
+	}
+	return v.Check(price, bid)
+}
+
+// Check checks a decrypted clearing price against its bid, notifying the
+// observer of every anomaly. If the policy of one of them is Reject, an
+// *AnomalyError is returned along with the result.
+// Checks depending on an unknown bid price or auction type are skipped.
+func (v *Validator) Check(price float64, bid Bid) (Result, error) {
+	result := Result{Price: price}
+	if bid.Price > 0 {
+		switch {
+		case price > bid.Price+v.tolerance:
+			result.Anomalies = append(result.Anomalies, AboveBid)
+		case bid.AuctionType == SecondPrice && price >= bid.Price-v.tolerance:
+			result.Anomalies = append(result.Anomalies, ExactBidSecondPrice)
+		}
+	}
+	if bid.Floor > 0 && price < bid.Floor-v.tolerance {
+		result.Anomalies = append(result.Anomalies, BelowFloor)
+	}
+
+	rejected := false
+	for _, anomaly := range result.Anomalies {
+		rejected = rejected || v.policies[anomaly] == Reject
+	}
+	if v.observer != nil {
+		for _, anomaly := range result.Anomalies {
+			v.observer.Observe(Event{Anomaly: anomaly, Price: price, Bid: bid, Rejected: rejected})
+		}
+	}
+	if rejected {
+		return result, &AnomalyError{Result: result, Bid: bid}
+	}
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package clearing
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func buildValidator(t *testing.T, options ...Option) *Validator {
+	t.Helper()
+	pricer, err := doubleclick.NewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	return NewValidator(pricer, options...)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     float64
+		bid       Bid
+		anomalies []Anomaly
+	}{
+		{"second price below bid", 1.2, Bid{Price: 1.5, AuctionType: SecondPrice, Floor: 1}, nil},
+		{"first price at bid", 1.5, Bid{Price: 1.5, AuctionType: FirstPrice}, nil},
+		{"above bid", 1.6, Bid{Price: 1.5, AuctionType: FirstPrice}, []Anomaly{AboveBid}},
+		{"above bid within tolerance", 1.5000001, Bid{Price: 1.5, AuctionType: FirstPrice}, nil},
+		{"below floor", 0.5, Bid{Price: 1.5, AuctionType: SecondPrice, Floor: 1}, []Anomaly{BelowFloor}},
+		{"exact bid in second price", 1.5, Bid{Price: 1.5, AuctionType: SecondPrice}, []Anomaly{ExactBidSecondPrice}},
+		{"exact bid below floor", 0.5, Bid{Price: 0.5, AuctionType: SecondPrice, Floor: 1}, []Anomaly{ExactBidSecondPrice, BelowFloor}},
+		{"unknown auction type", 1.5, Bid{Price: 1.5}, nil},
+		{"unknown bid", 10, Bid{AuctionType: SecondPrice}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup:
+			validator := NewValidator(nil)
+
+			// Execute:
+			result, err := validator.Check(tt.price, tt.bid)
+
+			// Verify:
+			assert.Nil(t, err)
+			assert.Equal(t, tt.price, result.Price)
+			assert.Equal(t, tt.anomalies, result.Anomalies)
+			assert.Equal(t, len(tt.anomalies) > 0, result.Flagged())
+		})
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	// Setup:
+	validator := buildValidator(t)
+
+	// Execute:
+	result, err := validator.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", Bid{Price: 1.354, AuctionType: SecondPrice})
+	_, decryptErr := validator.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA", Bid{Price: 2})
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result.Price)
+	assert.Equal(t, []Anomaly{ExactBidSecondPrice}, result.Anomalies)
+	assert.ErrorIs(t, decryptErr, doubleclick.ErrWrongSignature)
+	assert.False(t, errors.Is(decryptErr, ErrAnomaly))
+}
+
+func TestReject(t *testing.T) {
+	// Setup:
+	validator := buildValidator(t, WithPolicy(Reject), WithAnomalyPolicy(ExactBidSecondPrice, Flag))
+
+	// Execute:
+	aboveBid, aboveBidErr := validator.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", Bid{Price: 1.2, AuctionType: FirstPrice})
+	exactBid, exactBidErr := validator.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", Bid{Price: 1.354, AuctionType: SecondPrice})
+
+	// Verify:
+	assert.ErrorIs(t, aboveBidErr, ErrAnomaly)
+	assert.EqualError(t, aboveBidErr, "clearing: price 1.354 for a 1.2 first_price bid with a 0 floor is anomalous: above_bid")
+	var anomalyErr *AnomalyError
+	assert.True(t, errors.As(aboveBidErr, &anomalyErr))
+	assert.Equal(t, []Anomaly{AboveBid}, anomalyErr.Anomalies)
+	assert.Equal(t, 1.354, aboveBid.Price)
+	assert.Nil(t, exactBidErr)
+	assert.True(t, exactBid.Flagged())
+}
+
+func TestObserver(t *testing.T) {
+	// Setup:
+	var mu sync.Mutex
+	var events []Event
+	observer := ObserverFunc(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	validator := NewValidator(nil, WithObserver(observer), WithAnomalyPolicy(BelowFloor, Reject))
+	bid := Bid{Price: 0.5, AuctionType: SecondPrice, Floor: 1}
+
+	// Execute:
+	_, _ = validator.Check(1.2, Bid{Price: 1.5, AuctionType: SecondPrice})
+	_, err := validator.Check(0.5, bid)
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrAnomaly)
+	assert.Equal(t, []Event{
+		{Anomaly: ExactBidSecondPrice, Price: 0.5, Bid: bid, Rejected: true},
+		{Anomaly: BelowFloor, Price: 0.5, Bid: bid, Rejected: true},
+	}, events)
+}
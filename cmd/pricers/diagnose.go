@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/benjaminch/pricers/config"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// diagnose tries permutations of a pricer configuration against sample
+// tokens given as arguments, or read from stdin one per line.
+// It exits with 1 if the configuration doesn't validate them.
+func diagnose(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diagnose", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "configuration file to read the pricer from")
+	pricerID := flags.String("pricer", "", "exchange ID of the pricer in the configuration file")
+	var keys keyFlags
+	keys.register(flags)
+	tokenCodec := flags.String("token-codec", config.DefaultTokenCodec, "codec tokens are encoded with, one of "+strings.Join(doubleclick.CodecNames(), ", ")+", when no configuration file is given")
+	lenientDecoding := flags.Bool("lenient-decoding", false, "tokens may be encoded with any codec, when no configuration file is given")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: pricers diagnose [flags] [token...]")
+		fmt.Fprintln(stderr, "Tokens are read from stdin, one per line, when none is given.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	keyConfig, pc, err := keyConfigFromFlags(*configPath, *pricerID, keys)
+	if err != nil {
+		fmt.Fprintln(stderr, "pricers diagnose:", err)
+		return 2
+	}
+	if *configPath == "" {
+		pc.TokenCodec, pc.LenientDecoding = *tokenCodec, *lenientDecoding
+	}
+	options, err := pc.DoubleClickOptions()
+	if err != nil {
+		fmt.Fprintln(stderr, "pricers diagnose:", err)
+		return 2
+	}
+
+	tokens, err := readTokens(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "pricers diagnose:", err)
+		return 2
+	}
+
+	d := doubleclick.Diagnose(keyConfig, tokens, options...)
+	fmt.Fprintln(stdout, d.Explanation)
+	fmt.Fprintln(stdout)
+	printCandidate(stdout, "configured", d.Configured, d)
+	for _, match := range d.Matches {
+		printCandidate(stdout, "candidate", match, d)
+	}
+	if !d.OK() {
+		return 1
+	}
+	return 0
+}
+
+// readTokens : Returns the tokens given as arguments, or read from stdin
+// one per line when none is given, blank lines being skipped.
+func readTokens(args []string, stdin io.Reader) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	var tokens []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		if token := strings.TrimSpace(scanner.Text()); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, scanner.Err()
+}
+
+// keyFlags are the flags giving the keys of a pricer when no configuration
+// file is given. Keys are read from environment variables or files, never
+// given as flag values, which other users may see.
+type keyFlags struct {
+	encryptionKeyEnv  string
+	encryptionKeyFile string
+	integrityKeyEnv   string
+	integrityKeyFile  string
+	isBase64Keys      bool
+	keyDecodingMode   string
+}
+
+// register defines the key flags in flags.
+func (kf *keyFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&kf.encryptionKeyEnv, "encryption-key-env", "", "environment variable holding the encryption key, when no configuration file is given")
+	flags.StringVar(&kf.encryptionKeyFile, "encryption-key-file", "", "file holding the encryption key, when no configuration file is given")
+	flags.StringVar(&kf.integrityKeyEnv, "integrity-key-env", "", "environment variable holding the integrity key, when no configuration file is given")
+	flags.StringVar(&kf.integrityKeyFile, "integrity-key-file", "", "file holding the integrity key, when no configuration file is given")
+	flags.BoolVar(&kf.isBase64Keys, "base64-keys", false, "keys are base64 encoded, when no configuration file is given")
+	flags.StringVar(&kf.keyDecodingMode, "key-decoding-mode", helpers.Utf8.String(), "key decoding mode, when no configuration file is given")
+}
+
+// keyConfigFromFlags : Returns the key configuration, and the configuration,
+// of the pricer of a configuration file if configPath is set, or given by the key flags.
+func keyConfigFromFlags(configPath string, pricerID string, keys keyFlags) (doubleclick.KeyConfig, config.PricerConfig, error) {
+	if configPath != "" {
+		return keyConfigFromFile(configPath, pricerID)
+	}
+	mode, err := helpers.ParseKeyDecodingMode(keys.keyDecodingMode)
+	if err != nil {
+		return doubleclick.KeyConfig{}, config.PricerConfig{}, err
+	}
+	encryptionKey, err := readSecret("encryption-key", keys.encryptionKeyEnv, keys.encryptionKeyFile)
+	if err != nil {
+		return doubleclick.KeyConfig{}, config.PricerConfig{}, err
+	}
+	integrityKey, err := readSecret("integrity-key", keys.integrityKeyEnv, keys.integrityKeyFile)
+	if err != nil {
+		return doubleclick.KeyConfig{}, config.PricerConfig{}, err
+	}
+	keyConfig := doubleclick.KeyConfig{EncryptionKey: encryptionKey, IntegrityKey: integrityKey, IsBase64Keys: keys.isBase64Keys, KeyDecodingMode: mode}
+	return keyConfig, config.PricerConfig{Protocol: doubleclick.Protocol}, nil
+}
+
+// keyConfigFromFile : Returns the key configuration, and the configuration,
+// of a doubleclick pricer described by a configuration file, its keys being resolved.
+func keyConfigFromFile(path string, id string) (doubleclick.KeyConfig, config.PricerConfig, error) {
+	cfg, err := config.ParseFile(path)
+	if err != nil {
+		return doubleclick.KeyConfig{}, config.PricerConfig{}, err
+	}
+	for _, pc := range cfg.Pricers {
+		if pc.ID != id {
+			continue
+		}
+		if pc.Protocol != doubleclick.Protocol {
+			return doubleclick.KeyConfig{}, config.PricerConfig{}, fmt.Errorf("pricer %q uses protocol %q, only %q can be diagnosed", id, pc.Protocol, doubleclick.Protocol)
+		}
+		keyConfig, err := pc.ResolveKeys()
+		return keyConfig, pc, err
+	}
+	return doubleclick.KeyConfig{}, config.PricerConfig{}, fmt.Errorf("no pricer %q in %s", id, path)
+}
+
+// printCandidate prints the settings of a candidate, keys left out.
+func printCandidate(w io.Writer, label string, c doubleclick.Candidate, d doubleclick.Diagnosis) {
+	fmt.Fprintf(w, "%-10s  key_decoding_mode=%-5s  base64_keys=%-5t  swapped=%-5t  ", label, c.KeyDecodingMode, c.IsBase64Keys, c.Swapped)
+	if c.Err != nil {
+		fmt.Fprintf(w, "keys cannot be decoded: %s\n", c.Err)
+		return
+	}
+	fmt.Fprintf(w, "valid %d/%d\n", c.Valid, d.Tokens-d.Malformed)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	encryptionKey = "ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU"
+	integrityKey  = "vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U"
+)
+
+var tokens = []string{
+	"anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg",
+	"ce131TRp7waIZI2qOiRr2DMm2sSIeGh_wIAwVQ",
+}
+
+// keyEnvFlags sets keys in the environment, returning the flags reading them.
+func keyEnvFlags(t *testing.T, encryptionKey string, integrityKey string) []string {
+	t.Setenv("PRICERS_TEST_ENCRYPTION_KEY", encryptionKey)
+	t.Setenv("PRICERS_TEST_INTEGRITY_KEY", integrityKey)
+	return []string{"-encryption-key-env", "PRICERS_TEST_ENCRYPTION_KEY", "-integrity-key-env", "PRICERS_TEST_INTEGRITY_KEY"}
+}
+
+func TestDiagnoseWithFlags(t *testing.T) {
+	// Setup:
+	keys := keyEnvFlags(t, encryptionKey, integrityKey)
+
+	// Execute:
+	code, stdout, _ := runCommand("", append(append([]string{"diagnose"}, keys...), append([]string{"-base64-keys"}, tokens...)...)...)
+
+	// Verify:
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "The configuration validates the signature of all 2 well-formed sample tokens.")
+	assert.Contains(t, stdout, "configured  key_decoding_mode=utf-8  base64_keys=true   swapped=false  valid 2/2")
+	assert.NotContains(t, stdout, encryptionKey)
+}
+
+func TestDiagnoseSwappedKeysFromStdin(t *testing.T) {
+	// Setup:
+	dir := t.TempDir()
+	encryptionKeyPath := filepath.Join(dir, "encryption.key")
+	integrityKeyPath := filepath.Join(dir, "integrity.key")
+	assert.NoError(t, os.WriteFile(encryptionKeyPath, []byte(integrityKey+"\n"), 0o600))
+	assert.NoError(t, os.WriteFile(integrityKeyPath, []byte(encryptionKey+"\n"), 0o600))
+
+	// Execute:
+	code, stdout, _ := runCommand(tokens[0]+"\n\n"+tokens[1]+"\n", "diagnose",
+		"-encryption-key-file", encryptionKeyPath,
+		"-integrity-key-file", integrityKeyPath,
+		"-base64-keys")
+
+	// Verify:
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "Every signature validates when the encryption and integrity keys are swapped.")
+	assert.Contains(t, stdout, "candidate   key_decoding_mode=utf-8  base64_keys=true   swapped=true   valid 2/2")
+}
+
+func TestDiagnoseWithConfigFile(t *testing.T) {
+	// Setup:
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: `+encryptionKey+`
+    integrity_key: `+integrityKey+`
+`), 0o600))
+
+	// Execute:
+	code, stdout, _ := runCommand("", append([]string{"diagnose", "-config", path, "-pricer", "adx"}, tokens...)...)
+	missingCode, _, missingStderr := runCommand("", "diagnose", "-config", path, "-pricer", "other", tokens[0])
+
+	// Verify:
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "Every signature validates when base64 keys is true instead of false.")
+	assert.Equal(t, 2, missingCode)
+	assert.Contains(t, missingStderr, `no pricer "other"`)
+}
+
+func TestDiagnoseWithTokenCodec(t *testing.T) {
+	// Setup:
+	var hexTokens []string
+	for _, token := range tokens {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		assert.NoError(t, err)
+		hexTokens = append(hexTokens, hex.EncodeToString(decoded))
+	}
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: `+encryptionKey+`
+    integrity_key: `+integrityKey+`
+    base64_keys: true
+    token_codec: hex
+`), 0o600))
+
+	keys := keyEnvFlags(t, encryptionKey, integrityKey)
+
+	// Execute:
+	flagsCode, flagsStdout, _ := runCommand("", append(append([]string{"diagnose"}, keys...), append([]string{"-base64-keys", "-token-codec", "hex"}, hexTokens...)...)...)
+	fileCode, fileStdout, _ := runCommand("", append([]string{"diagnose", "-config", path, "-pricer", "adx"}, hexTokens...)...)
+	defaultCode, defaultStdout, _ := runCommand("", append(append([]string{"diagnose"}, keys...), append([]string{"-base64-keys"}, hexTokens...)...)...)
+	codecCode, _, codecStderr := runCommand("", append(append([]string{"diagnose"}, keys...), "-token-codec", "base32", tokens[0])...)
+
+	// Verify:
+	assert.Equal(t, 0, flagsCode)
+	assert.Contains(t, flagsStdout, "The configuration validates the signature of all 2 well-formed sample tokens.")
+	assert.Equal(t, 0, fileCode)
+	assert.Contains(t, fileStdout, "The configuration validates the signature of all 2 well-formed sample tokens.")
+	assert.Equal(t, 1, defaultCode)
+	assert.Contains(t, defaultStdout, "2 of 2 sample tokens are not encrypted prices")
+	assert.Equal(t, 2, codecCode)
+	assert.Contains(t, codecStderr, `unknown codec "base32"`)
+}
+
+func TestDiagnoseUsageErrors(t *testing.T) {
+	// Setup:
+	keys := keyEnvFlags(t, encryptionKey, integrityKey)
+
+	// Execute:
+	missingCode, _, missingStderr := runCommand("", "diagnose", "-encryption-key-env", "PRICERS_TEST_ENCRYPTION_KEY", tokens[0])
+	bothCode, _, bothStderr := runCommand("", append(append([]string{"diagnose"}, keys...), "-encryption-key-file", "encryption.key", tokens[0])...)
+	unsetCode, _, unsetStderr := runCommand("", "diagnose", "-encryption-key-env", "PRICERS_TEST_UNSET", "-integrity-key-env", "PRICERS_TEST_INTEGRITY_KEY", tokens[0])
+	plaintextCode, _, _ := runCommand("", "diagnose", "-encryption-key", encryptionKey, "-integrity-key", integrityKey, tokens[0])
+	modeCode, _, _ := runCommand("", append(append([]string{"diagnose"}, keys...), "-key-decoding-mode", "latin-1")...)
+	flagCode, _, _ := runCommand("", "diagnose", "-unknown")
+
+	// Verify:
+	assert.Equal(t, 2, missingCode)
+	assert.Contains(t, missingStderr, "exactly one of -integrity-key-env or -integrity-key-file is required")
+	assert.Equal(t, 2, bothCode)
+	assert.Contains(t, bothStderr, "exactly one of -encryption-key-env or -encryption-key-file is required")
+	assert.Equal(t, 2, unsetCode)
+	assert.Contains(t, unsetStderr, `encryption-key: environment variable "PRICERS_TEST_UNSET" is not set`)
+	assert.Equal(t, 2, plaintextCode)
+	assert.Equal(t, 2, modeCode)
+	assert.Equal(t, 2, flagCode)
+}
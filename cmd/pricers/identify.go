@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/benjaminch/pricers/config"
+	"github.com/benjaminch/pricers/identify"
+)
+
+// identifyTokens ranks the protocols, and exchanges of a configuration file,
+// sample tokens given as arguments, or read from stdin one per line, may
+// have been issued with. It exits with 1 if a token has no candidate.
+func identifyTokens(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("identify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "configuration file whose pricers tokens are trial-verified against")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: pricers identify [flags] [token...]")
+		fmt.Fprintln(stderr, "Tokens are read from stdin, one per line, when none is given.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var exchanges []identify.Exchange
+	if *configPath != "" {
+		var err error
+		if exchanges, err = exchangesFromFile(*configPath); err != nil {
+			fmt.Fprintln(stderr, "pricers identify:", err)
+			return 2
+		}
+	}
+	tokens, err := readTokens(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "pricers identify:", err)
+		return 2
+	}
+
+	identifier := identify.NewIdentifier(exchanges...)
+	code := 0
+	for i, token := range tokens {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "token %d:\n", i+1)
+		candidates := identifier.Identify(token)
+		if len(candidates) == 0 {
+			fmt.Fprintln(stdout, "  not an encrypted price of a known protocol")
+			code = 1
+		}
+		for _, c := range candidates {
+			fmt.Fprintf(stdout, "  %.2f  protocol=%s", c.Score, c.Protocol)
+			if c.Codec != "" {
+				fmt.Fprintf(stdout, "  codec=%s", c.Codec)
+			}
+			if c.Verified {
+				fmt.Fprintf(stdout, "  exchange=%s  verified", c.Exchange)
+			}
+			fmt.Fprintln(stdout)
+			for _, reason := range c.Reasons {
+				fmt.Fprintf(stdout, "        %s\n", reason)
+			}
+		}
+	}
+	return code
+}
+
+// exchangesFromFile : Returns the exchanges of a configuration file, their pricers being built.
+func exchangesFromFile(path string) ([]identify.Exchange, error) {
+	cfg, err := config.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	exchanges := make([]identify.Exchange, 0, len(cfg.Pricers))
+	for _, pc := range cfg.Pricers {
+		exchanges = append(exchanges, identify.Exchange{ID: pc.ID, Protocol: pc.Protocol, Decrypter: set[pc.ID]})
+	}
+	return exchanges, nil
+}
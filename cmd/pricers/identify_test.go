@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentify(t *testing.T) {
+	// Execute:
+	code, stdout, _ := runCommand(tokens[0]+"\n", "identify")
+
+	// Verify:
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "token 1:\n  0.70  protocol=doubleclick  codec=base64url\n")
+	assert.Contains(t, stdout, "        decodes to 28 bytes: 16 bytes IV, 8 bytes price and 4 bytes signature\n")
+	assert.NotContains(t, stdout, "verified")
+}
+
+func TestIdentifyWithConfigFile(t *testing.T) {
+	// Setup:
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: `+encryptionKey+`
+    integrity_key: `+integrityKey+`
+    base64_keys: true
+`), 0o600))
+
+	// Execute:
+	code, stdout, _ := runCommand("", "identify", "-config", path, tokens[0], "too short")
+
+	// Verify:
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "token 1:\n  1.00  protocol=doubleclick  codec=base64url  exchange=adx  verified\n")
+	assert.Contains(t, stdout, "token 2:\n  not an encrypted price of a known protocol\n")
+	assert.NotContains(t, stdout, encryptionKey)
+}
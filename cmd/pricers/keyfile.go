@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/benjaminch/pricers/config"
+	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/keyfile"
+)
+
+// seal writes a key file holding the keys of a pricer configuration,
+// or given as flags, sealed with a passphrase.
+func seal(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("seal", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "configuration file to read the keys from")
+	pricerID := flags.String("pricer", "", "exchange ID of the pricer in the configuration file")
+	var keyFlags keyFlags
+	keyFlags.register(flags)
+	exchange := flags.String("exchange", "", "exchange the keys were issued by, the pricer ID by default")
+	comment := flags.String("comment", "", "free text stored along with the keys")
+	passphraseEnv := flags.String("passphrase-env", "", "environment variable holding the passphrase")
+	passphraseFile := flags.String("passphrase-file", "", "file holding the passphrase")
+	output := flags.String("o", "", "key file to write, stdout by default")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: pricers seal [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(stderr, "pricers seal:", err)
+		return 2
+	}
+
+	keyConfig, _, err := keyConfigFromFlags(*configPath, *pricerID, keyFlags)
+	if err != nil {
+		return fail(err)
+	}
+	passphrase, err := readPassphrase("passphrase", *passphraseEnv, *passphraseFile)
+	if err != nil {
+		return fail(err)
+	}
+	keys := keyfile.Keys{KeyDecodingMode: keyConfig.KeyDecodingMode, Base64Keys: keyConfig.IsBase64Keys}
+	if keys.EncryptionKey, err = helpers.ParseKey(keyConfig.EncryptionKey, keyConfig.IsBase64Keys, keyConfig.KeyDecodingMode); err != nil {
+		return fail(fmt.Errorf("encryption key: %w", err))
+	}
+	if keys.IntegrityKey, err = helpers.ParseKey(keyConfig.IntegrityKey, keyConfig.IsBase64Keys, keyConfig.KeyDecodingMode); err != nil {
+		return fail(fmt.Errorf("integrity key: %w", err))
+	}
+	defer keys.Zero()
+	keys.Metadata = keyfile.Metadata{Exchange: *exchange, Comment: *comment}
+	if keys.Metadata.Exchange == "" {
+		keys.Metadata.Exchange = *pricerID
+	}
+
+	data, err := keyfile.Seal(keys, passphrase)
+	if err != nil {
+		return fail(err)
+	}
+	if err := writeKeyFile(*output, data, stdout); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// unseal prints the keys held by a key file, in configuration file syntax.
+func unseal(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("unseal", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	passphraseEnv := flags.String("passphrase-env", "", "environment variable holding the passphrase")
+	passphraseFile := flags.String("passphrase-file", "", "file holding the passphrase")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: pricers unseal [flags] <key file>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	passphrase, err := readPassphrase("passphrase", *passphraseEnv, *passphraseFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "pricers unseal:", err)
+		return 2
+	}
+	keys, err := keyfile.Load(flags.Arg(0), passphrase)
+	if err != nil {
+		fmt.Fprintln(stderr, "pricers unseal:", err)
+		return 1
+	}
+	defer keys.Zero()
+
+	encryptionKey, integrityKey := keys.Encode()
+	fmt.Fprintf(stdout, "# exchange %q, sealed %s", keys.Metadata.Exchange, keys.Metadata.CreatedAt.Format(time.RFC3339))
+	if keys.Metadata.Comment != "" {
+		fmt.Fprintf(stdout, ", %s", keys.Metadata.Comment)
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "encryption_key: %q  # fingerprint %s\n", encryptionKey, keys.EncryptionKey.Fingerprint())
+	fmt.Fprintf(stdout, "integrity_key: %q  # fingerprint %s\n", integrityKey, keys.IntegrityKey.Fingerprint())
+	fmt.Fprintf(stdout, "key_decoding_mode: %s\n", keys.KeyDecodingMode)
+	fmt.Fprintf(stdout, "base64_keys: %t\n", keys.Base64Keys)
+	return 0
+}
+
+// rotate seals a key file with a new passphrase, in place unless -o is given.
+func rotate(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	passphraseEnv := flags.String("passphrase-env", "", "environment variable holding the current passphrase")
+	passphraseFile := flags.String("passphrase-file", "", "file holding the current passphrase")
+	newPassphraseEnv := flags.String("new-passphrase-env", "", "environment variable holding the new passphrase")
+	newPassphraseFile := flags.String("new-passphrase-file", "", "file holding the new passphrase")
+	output := flags.String("o", "", "key file to write, the rotated one by default")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: pricers rotate [flags] <key file>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	fail := func(code int, err error) int {
+		fmt.Fprintln(stderr, "pricers rotate:", err)
+		return code
+	}
+
+	passphrase, err := readPassphrase("passphrase", *passphraseEnv, *passphraseFile)
+	if err != nil {
+		return fail(2, err)
+	}
+	newPassphrase, err := readPassphrase("new-passphrase", *newPassphraseEnv, *newPassphraseFile)
+	if err != nil {
+		return fail(2, err)
+	}
+	path := flags.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail(2, err)
+	}
+	rotated, err := keyfile.Rotate(data, passphrase, newPassphrase)
+	if err != nil {
+		return fail(1, fmt.Errorf("%s: %w", path, err))
+	}
+	if *output == "" {
+		*output = path
+	}
+	if err := writeKeyFile(*output, rotated, stdout); err != nil {
+		return fail(2, err)
+	}
+	return 0
+}
+
+// readPassphrase : Returns the passphrase read from the environment
+// variable or the file given by the -<name>-env or -<name>-file flags.
+func readPassphrase(name string, env string, file string) ([]byte, error) {
+	passphrase, err := readSecret(name, env, file)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(passphrase), nil
+}
+
+// readSecret : Returns the secret read from the environment variable or
+// the file given by the -<name>-env or -<name>-file flags.
+// Secrets are never given as flag values, which other users may see.
+func readSecret(name string, env string, file string) (string, error) {
+	if (env == "") == (file == "") {
+		return "", fmt.Errorf("exactly one of -%s-env or -%s-file is required", name, name)
+	}
+	secret, err := config.KeySource{Env: env, File: file}.Resolve()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// writeKeyFile writes a key file to path, atomically and readable by its
+// owner only, or to stdout if path is empty.
+func writeKeyFile(path string, data []byte, stdout io.Writer) error {
+	data = append(data, '\n')
+	if path == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
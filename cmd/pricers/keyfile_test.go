@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealUnseal(t *testing.T) {
+	// Setup:
+	t.Setenv("PRICERS_TEST_PASSPHRASE", "correct horse battery staple")
+	keys := keyEnvFlags(t, encryptionKey, integrityKey)
+	path := filepath.Join(t.TempDir(), "adx.keys")
+
+	// Execute:
+	sealCode, _, sealStderr := runCommand("", append([]string{"seal"}, append(keys,
+		"-base64-keys",
+		"-exchange", "adx",
+		"-comment", "Google test keys",
+		"-passphrase-env", "PRICERS_TEST_PASSPHRASE",
+		"-o", path)...)...)
+	unsealCode, stdout, _ := runCommand("", "unseal", "-passphrase-env", "PRICERS_TEST_PASSPHRASE", path)
+
+	// Verify:
+	assert.Equal(t, 0, sealCode, sealStderr)
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), encryptionKey)
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	assert.Equal(t, 0, unsealCode)
+	assert.Contains(t, stdout, `# exchange "adx", sealed `)
+	assert.Contains(t, stdout, ", Google test keys\n")
+	assert.Contains(t, stdout, `encryption_key: "`+encryptionKey+`"  # fingerprint 06ae61`)
+	assert.Contains(t, stdout, `integrity_key: "`+integrityKey+`"  # fingerprint 8aa8d3`)
+	assert.Contains(t, stdout, "key_decoding_mode: utf-8\nbase64_keys: true\n")
+}
+
+func TestSealFromConfigFile(t *testing.T) {
+	// Setup:
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "pricers.yaml")
+	passphrasePath := filepath.Join(dir, "passphrase")
+	assert.NoError(t, os.WriteFile(configPath, []byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: `+encryptionKey+`
+    integrity_key: `+integrityKey+`
+    base64_keys: true
+`), 0o600))
+	assert.NoError(t, os.WriteFile(passphrasePath, []byte("correct horse battery staple\n"), 0o600))
+
+	// Execute:
+	code, stdout, stderr := runCommand("", "seal", "-config", configPath, "-pricer", "adx", "-passphrase-file", passphrasePath)
+
+	// Verify:
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, `"encryption_key": "06ae61"`)
+	assert.NotContains(t, stdout, encryptionKey)
+}
+
+func TestSealRequiresPassphrase(t *testing.T) {
+	// Setup:
+	keys := keyEnvFlags(t, encryptionKey, integrityKey)
+
+	// Execute:
+	code, stdout, stderr := runCommand("", append([]string{"seal"}, keys...)...)
+
+	// Verify:
+	assert.Equal(t, 2, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "exactly one of -passphrase-env or -passphrase-file is required")
+}
+
+func TestRotate(t *testing.T) {
+	// Setup:
+	t.Setenv("PRICERS_TEST_PASSPHRASE", "correct horse battery staple")
+	t.Setenv("PRICERS_TEST_NEW_PASSPHRASE", "new passphrase")
+	path := filepath.Join(t.TempDir(), "adx.keys")
+	content, err := os.ReadFile("../../keyfile/testdata/adx.keys")
+	assert.Nil(t, err)
+	assert.NoError(t, os.WriteFile(path, content, 0o600))
+
+	// Execute:
+	code, _, stderr := runCommand("", "rotate",
+		"-passphrase-env", "PRICERS_TEST_PASSPHRASE",
+		"-new-passphrase-env", "PRICERS_TEST_NEW_PASSPHRASE",
+		path)
+	oldCode, _, oldStderr := runCommand("", "unseal", "-passphrase-env", "PRICERS_TEST_PASSPHRASE", path)
+	newCode, stdout, _ := runCommand("", "unseal", "-passphrase-env", "PRICERS_TEST_NEW_PASSPHRASE", path)
+
+	// Verify:
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, 1, oldCode)
+	assert.Contains(t, oldStderr, "Wrong passphrase or tampered key file")
+	assert.Equal(t, 0, newCode)
+	assert.Contains(t, stdout, `# exchange "adx", sealed 2024-01-02T03:04:05Z, Google test keys`)
+}
@@ -0,0 +1,60 @@
+// Command pricers provides tools to operate pricers.
+//
+// Usage:
+//
+//	pricers <command> [flags] [arguments]
+//
+// Run `pricers <command> -h` for the flags of a command.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command runs with the arguments following its name, returning the exit code.
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"diagnose": {"Tells why encrypted prices fail to decrypt with a pricer configuration", diagnose},
+	"identify": {"Ranks the protocols and exchanges encrypted prices may have been issued with", identifyTokens},
+	"seal":     {"Writes a key file holding keys sealed with a passphrase", seal},
+	"unseal":   {"Prints the keys held by a key file", unseal},
+	"rotate":   {"Seals a key file with a new passphrase", rotate},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "pricers: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: pricers <command> [flags] [arguments]")
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
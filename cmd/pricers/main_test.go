@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runCommand runs the command line, returning its exit code and outputs.
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewBufferString(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsage(t *testing.T) {
+	// Execute:
+	code, _, stderr := runCommand("")
+	unknownCode, _, unknownStderr := runCommand("", "unknown")
+
+	// Verify:
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "diagnose")
+	assert.Equal(t, 2, unknownCode)
+	assert.Contains(t, unknownStderr, `unknown command "unknown"`)
+}
@@ -0,0 +1,105 @@
+// Package openrtbpricers exposes the API of github.com/benjaminch/openrtb-pricers,
+// the predecessor of this library, on top of the doubleclick package, so that
+// services can migrate one at a time.
+//
+// Migrating a service means replacing:
+//
+//	import (
+//		"github.com/benjaminch/openrtb-pricers/doubleclick"
+//		"github.com/benjaminch/openrtb-pricers/helpers"
+//	)
+//
+// with:
+//
+//	import (
+//		doubleclick "github.com/benjaminch/pricers/compat/openrtbpricers"
+//		"github.com/benjaminch/pricers/helpers"
+//	)
+//
+// Encrypted prices are byte for byte the same. Errors differ: they are the
+// ones of the doubleclick package, e.g. a wrong signature fails with an
+// error matching doubleclick.ErrWrongSignature instead of "Failed to decrypt",
+// and malformed encrypted prices fail instead of panicking.
+package openrtbpricers
+
+import (
+	"sync"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// DoubleClickPricer implements the openrtb-pricers DoubleClickPricer API.
+// A DoubleClickPricer is safe for concurrent use.
+type DoubleClickPricer struct {
+	encryptionKey   string
+	integrityKey    string
+	isBase64Keys    bool
+	keyDecodingMode helpers.KeyDecodingMode
+	scaleFactor     float64
+
+	pricer *doubleclick.DoubleClickPricer
+
+	// debug is the pricer used for calls in debug mode, built on first use.
+	debugOnce sync.Once
+	debug     *doubleclick.DoubleClickPricer
+	debugErr  error
+}
+
+// NewDoubleClickPricer returns a DoubleClickPricer, taking the parameters of
+// openrtb-pricers' doubleclick.NewDoubleClickPricer.
+// In debug mode, debug events are written as text to stderr.
+func NewDoubleClickPricer(
+	encryptionKey string,
+	integrityKey string,
+	isBase64Keys bool,
+	keyDecodingMode helpers.KeyDecodingMode,
+	scaleFactor float64,
+	isDebugMode bool) (*DoubleClickPricer, error) {
+	pricer, err := doubleclick.NewDoubleClickPricer(encryptionKey, integrityKey, isBase64Keys, keyDecodingMode, scaleFactor, isDebugMode)
+	if err != nil {
+		return nil, err
+	}
+	return &DoubleClickPricer{
+		encryptionKey:   encryptionKey,
+		integrityKey:    integrityKey,
+		isBase64Keys:    isBase64Keys,
+		keyDecodingMode: keyDecodingMode,
+		scaleFactor:     scaleFactor,
+		pricer:          pricer,
+	}, nil
+}
+
+// Encrypt encrypts a clear price and a given seed.
+func (dc *DoubleClickPricer) Encrypt(seed string, price float64, isDebugMode bool) (string, error) {
+	pricer, err := dc.get(isDebugMode)
+	if err != nil {
+		return "", err
+	}
+	return pricer.Encrypt(seed, price)
+}
+
+// Decrypt decrypts an encrypted price.
+func (dc *DoubleClickPricer) Decrypt(encryptedPrice string, isDebugMode bool) (float64, error) {
+	pricer, err := dc.get(isDebugMode)
+	if err != nil {
+		return 0, err
+	}
+	return pricer.Decrypt(encryptedPrice)
+}
+
+// Pricer : Returns the underlying pricer, for code migrating to the doubleclick package.
+func (dc *DoubleClickPricer) Pricer() *doubleclick.DoubleClickPricer {
+	return dc.pricer
+}
+
+// get : Returns the pricer to use for a call, in debug mode or not.
+func (dc *DoubleClickPricer) get(isDebugMode bool) (*doubleclick.DoubleClickPricer, error) {
+	if !isDebugMode {
+		return dc.pricer, nil
+	}
+	dc.debugOnce.Do(func() {
+		dc.debug, dc.debugErr = doubleclick.NewDoubleClickPricer(dc.encryptionKey, dc.integrityKey, dc.isBase64Keys, dc.keyDecodingMode, dc.scaleFactor, true)
+	})
+	return dc.debug, dc.debugErr
+}
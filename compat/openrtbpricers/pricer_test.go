@@ -0,0 +1,218 @@
+package openrtbpricers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	olddoubleclick "github.com/benjaminch/openrtb-pricers/doubleclick"
+	oldhelpers "github.com/benjaminch/openrtb-pricers/helpers"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// differentialRuns is the number of random keys, seeds and prices tried.
+const differentialRuns = 500
+
+// keyConfig is a way of giving keys to both libraries.
+type keyConfig struct {
+	encryptionKey string
+	integrityKey  string
+	isBase64Keys  bool
+	mode          helpers.KeyDecodingMode
+}
+
+// oldMode : Returns the openrtb-pricers key decoding mode matching mode.
+func (kc keyConfig) oldMode() oldhelpers.KeyDecodingMode {
+	if kc.mode == helpers.Hexa {
+		return oldhelpers.Hexa
+	}
+	return oldhelpers.Utf8
+}
+
+// randomKeyConfig : Returns random 32 bytes keys, given in one of the
+// forms both libraries accept.
+func randomKeyConfig(r *rand.Rand) keyConfig {
+	encryptionKey, integrityKey := make([]byte, 32), make([]byte, 32)
+	r.Read(encryptionKey)
+	r.Read(integrityKey)
+
+	switch r.Intn(4) {
+	case 0:
+		return keyConfig{hex.EncodeToString(encryptionKey), hex.EncodeToString(integrityKey), false, helpers.Hexa}
+	case 1:
+		return keyConfig{base64.RawURLEncoding.EncodeToString(encryptionKey), base64.RawURLEncoding.EncodeToString(integrityKey), true, helpers.Utf8}
+	case 2:
+		return keyConfig{
+			base64.URLEncoding.EncodeToString([]byte(hex.EncodeToString(encryptionKey))),
+			base64.URLEncoding.EncodeToString([]byte(hex.EncodeToString(integrityKey))),
+			true, helpers.Hexa}
+	default:
+		return keyConfig{string(encryptionKey), string(integrityKey), false, helpers.Utf8}
+	}
+}
+
+func randomSeed(r *rand.Rand) string {
+	seed := make([]byte, r.Intn(64))
+	r.Read(seed)
+	return string(seed)
+}
+
+func randomPrice(r *rand.Rand) float64 {
+	switch r.Intn(3) {
+	case 0:
+		return 0
+	case 1:
+		return float64(r.Intn(100000)) / 1000
+	default:
+		return r.Float64() * 10000
+	}
+}
+
+func TestDifferential(t *testing.T) {
+	// Setup:
+	r := rand.New(rand.NewSource(44))
+	scaleFactors := []float64{1000000, 100000, 2000000}
+
+	for i := 0; i < differentialRuns; i++ {
+		config := randomKeyConfig(r)
+		scaleFactor := scaleFactors[r.Intn(len(scaleFactors))]
+		seed := randomSeed(r)
+		price := randomPrice(r)
+
+		oldPricer, err := olddoubleclick.NewDoubleClickPricer(config.encryptionKey, config.integrityKey, config.isBase64Keys, config.oldMode(), scaleFactor, false)
+		assert.Nil(t, err)
+		newPricer, err := NewDoubleClickPricer(config.encryptionKey, config.integrityKey, config.isBase64Keys, config.mode, scaleFactor, false)
+		assert.Nil(t, err)
+
+		// Execute:
+		oldEncrypted, oldErr := oldPricer.Encrypt(seed, price, false)
+		newEncrypted, newErr := newPricer.Encrypt(seed, price, false)
+
+		// Verify:
+		assert.Nil(t, oldErr)
+		assert.Nil(t, newErr)
+		if !assert.Equal(t, oldEncrypted, newEncrypted, "run %d: seed %q, price %v, scale factor %v", i, seed, price, scaleFactor) {
+			continue
+		}
+
+		// Execute:
+		oldDecrypted, oldErr := oldPricer.Decrypt(newEncrypted, false)
+		newDecrypted, newErr := newPricer.Decrypt(oldEncrypted, false)
+
+		// Verify:
+		assert.Nil(t, oldErr)
+		assert.Nil(t, newErr)
+		assert.Equal(t, oldDecrypted, newDecrypted, "run %d: encrypted price %s", i, oldEncrypted)
+	}
+}
+
+func TestDifferentialWrongSignature(t *testing.T) {
+	// Setup:
+	r := rand.New(rand.NewSource(4444))
+
+	for i := 0; i < differentialRuns; i++ {
+		config := randomKeyConfig(r)
+		oldPricer, err := olddoubleclick.NewDoubleClickPricer(config.encryptionKey, config.integrityKey, config.isBase64Keys, config.oldMode(), 1000000, false)
+		assert.Nil(t, err)
+		newPricer, err := NewDoubleClickPricer(config.encryptionKey, config.integrityKey, config.isBase64Keys, config.mode, 1000000, false)
+		assert.Nil(t, err)
+
+		encrypted, err := newPricer.Encrypt(randomSeed(r), randomPrice(r), false)
+		assert.Nil(t, err)
+		// Flip a bit of the encrypted price or of the signature, keeping the IV
+		// so that the pad is the same.
+		decoded, err := base64.RawURLEncoding.DecodeString(encrypted)
+		assert.Nil(t, err)
+		decoded[16+r.Intn(12)] ^= 1 << uint(r.Intn(8))
+		tampered := base64.RawURLEncoding.EncodeToString(decoded)
+
+		// Execute:
+		_, oldErr := oldPricer.Decrypt(tampered, false)
+		_, newErr := newPricer.Decrypt(tampered, false)
+
+		// Verify:
+		assert.NotNil(t, oldErr, "run %d: encrypted price %s", i, tampered)
+		assert.ErrorIs(t, newErr, doubleclick.ErrWrongSignature, "run %d: encrypted price %s", i, tampered)
+	}
+}
+
+func TestDecryptMalformed(t *testing.T) {
+	// Setup:
+	pricer, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+
+	// Execute:
+	// openrtb-pricers panics on encrypted prices shorter than 28 bytes.
+	_, err = pricer.Decrypt("1B2M2Y8AsgTpgAmY", false)
+
+	// Verify:
+	assert.ErrorIs(t, err, doubleclick.ErrWrongSize)
+}
+
+func TestDebugMode(t *testing.T) {
+	// Setup:
+	pricer, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+
+	// Execute:
+	encrypted, err := pricer.Encrypt("", 1.354, true)
+	assert.Nil(t, err)
+	decrypted, err := pricer.Decrypt(encrypted, true)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", encrypted)
+	assert.Equal(t, 1.354, decrypted)
+}
+
+func FuzzDifferential(f *testing.F) {
+	oldPricer, err := olddoubleclick.NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, oldhelpers.Hexa, 1000000, false)
+	if err != nil {
+		f.Fatal(err)
+	}
+	newPricer, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add("", uint32(1354000))
+	f.Add("38b9be8b-2bd7-4d1c-a8e9-0c5f10a7a1b4", uint32(0))
+
+	f.Fuzz(func(t *testing.T, seed string, micros uint32) {
+		price := float64(micros) / 1000000
+		oldEncrypted, err := oldPricer.Encrypt(seed, price, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newEncrypted, err := newPricer.Encrypt(seed, price, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oldEncrypted != newEncrypted {
+			t.Fatalf("Encrypt(%q, %v) = %s, openrtb-pricers gives %s", seed, price, newEncrypted, oldEncrypted)
+		}
+		oldDecrypted, _ := oldPricer.Decrypt(newEncrypted, false)
+		newDecrypted, err := newPricer.Decrypt(oldEncrypted, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oldDecrypted != newDecrypted {
+			t.Fatalf("Decrypt(%s) = %v, openrtb-pricers gives %v", newEncrypted, newDecrypted, oldDecrypted)
+		}
+	})
+}
@@ -0,0 +1,348 @@
+// Package config loads a declarative description of the pricers used for
+// every exchange, from a YAML or JSON file.
+//
+// Example:
+//
+//	version: 1
+//	role: decrypt
+//	pricers:
+//	  adx:
+//	    protocol: doubleclick
+//	    encryption_key: {env: ADX_ENCRYPTION_KEY}
+//	    integrity_key: {file: /etc/keys/adx_integrity, fingerprint: 8aa8d3}
+//	    key_decoding_mode: utf-8
+//	    base64_keys: true
+//	    scale_factor: 1000000
+//	    limits: {min_price: 0, max_price: 100}
+//	    token_codec: base64url
+//	    lenient_decoding: false
+//	  openx:
+//	    protocol: doubleclick
+//	    key_file: {path: /etc/keys/openx.keys, passphrase: {env: OPENX_PASSPHRASE}}
+//
+// Relative key_file paths are relative to the directory of the configuration file.
+// The JSON schema of the file is available as Schema.
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/keyfile"
+)
+
+// Version is the configuration file version supported.
+const Version = 1
+
+// Schema is the JSON schema of the configuration file.
+//
+//go:embed schema.json
+var Schema []byte
+
+// KeySource describes where a key is read from.
+// Exactly one of Value, Env and File must be set.
+type KeySource struct {
+	// Value is the key itself.
+	Value string
+	// Env is the name of the environment variable holding the key.
+	Env string
+	// File is the path of the file holding the key, surrounding spaces being trimmed.
+	File string
+	// Fingerprint is the expected fingerprint of the decoded key, if set.
+	// See helpers.Key.Fingerprint.
+	Fingerprint string
+
+	line int
+}
+
+// Resolve : Returns the key read from its source.
+func (ks KeySource) Resolve() (string, error) {
+	switch {
+	case ks.Value != "":
+		return ks.Value, nil
+	case ks.Env != "":
+		key, ok := os.LookupEnv(ks.Env)
+		if !ok || key == "" {
+			return "", fmt.Errorf("environment variable %q is not set", ks.Env)
+		}
+		return key, nil
+	case ks.File != "":
+		content, err := os.ReadFile(ks.File)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	return "", fmt.Errorf("key source is empty")
+}
+
+// KeyFile describes a key file holding both keys of a pricer, see package keyfile.
+type KeyFile struct {
+	// Path is the path of the key file.
+	Path string
+	// Passphrase is the source of the passphrase the key file is sealed with.
+	Passphrase KeySource
+
+	line int
+}
+
+// Limits holds the range of accepted prices, a zero MaxPrice meaning no upper limit.
+type Limits struct {
+	MinPrice float64
+	MaxPrice float64
+}
+
+// PricerConfig describes the pricer of an exchange.
+type PricerConfig struct {
+	// ID is the exchange ID the pricer is registered for.
+	ID string
+	// Protocol is the price encryption protocol, see Protocols.
+	Protocol      string
+	EncryptionKey KeySource
+	IntegrityKey  KeySource
+	// KeyFile, if set, holds the keys instead of EncryptionKey and IntegrityKey,
+	// along with how they are decoded, KeyDecodingMode and Base64Keys being ignored.
+	KeyFile         *KeyFile
+	KeyDecodingMode helpers.KeyDecodingMode
+	Base64Keys      bool
+	ScaleFactor     float64
+	Limits          Limits
+	// TokenCodec is the name of the codec encrypted prices are encoded with.
+	TokenCodec string
+	// LenientDecoding makes pricers accept encrypted prices encoded with any codec.
+	LenientDecoding bool
+
+	line int
+}
+
+// Config describes the pricers of every exchange.
+type Config struct {
+	Version int
+	// Role restricts what every pricer can do, RoleBoth by default.
+	Role Role
+	// Pricers are sorted by ID.
+	Pricers []PricerConfig
+}
+
+// Set holds ready to use pricers keyed by exchange ID.
+type Set map[string]pricers.Pricer
+
+// IDs : Returns the sorted exchange IDs of the set.
+func (s Set) IDs() []string {
+	ids := make([]string, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Zero retires the pricers of the set, zeroing the keys of the ones having
+// a Zero method, such as doubleclick.DoubleClickPricer.
+// The pricers must not be used afterwards.
+func (s Set) Zero() {
+	for _, pricer := range s {
+		zero(pricer)
+	}
+}
+
+// Load reads, validates and builds the pricers described by a YAML or JSON file.
+func Load(path string) (Set, error) {
+	cfg, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build()
+}
+
+// ParseFile reads, parses and validates a YAML or JSON file, see Parse.
+// Relative key_file paths are resolved against the directory of the file.
+func ParseFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.relativeTo(filepath.Dir(path))
+	return cfg, nil
+}
+
+// relativeTo resolves the relative key_file paths against dir, the
+// directory of the configuration file, if any.
+func (c *Config) relativeTo(dir string) {
+	if dir == "" {
+		return
+	}
+	for _, pc := range c.Pricers {
+		if pc.KeyFile != nil && pc.KeyFile.Path != "" && !filepath.IsAbs(pc.KeyFile.Path) {
+			pc.KeyFile.Path = filepath.Join(dir, pc.KeyFile.Path)
+		}
+	}
+}
+
+// Build returns the pricers described by the configuration.
+// Every pricer is built, errors being returned all together as Errors.
+func (c *Config) Build() (Set, error) {
+	var errs Errors
+	set := make(Set, len(c.Pricers))
+
+	for _, pc := range c.Pricers {
+		pricer, err := pc.Build()
+		if err != nil {
+			errs = append(errs, err.(Errors)...)
+			continue
+		}
+		set[pc.ID] = c.Role.restrict(pricer)
+	}
+
+	if len(errs) > 0 {
+		set.Zero()
+		return nil, errs
+	}
+	return set, nil
+}
+
+// Build returns the pricer described by the configuration.
+// Errors are returned as Errors.
+func (pc PricerConfig) Build() (pricers.Pricer, error) {
+	builder, ok := lookupProtocol(pc.Protocol)
+	if !ok {
+		return nil, Errors{pc.errorf(pc.line, "protocol", "unknown protocol %q", pc.Protocol)}
+	}
+
+	if keyBuilder, ok := keyBuilders[pc.Protocol]; ok && pc.KeyFile != nil {
+		return pc.buildWithKeys(keyBuilder)
+	}
+
+	keys, err := pc.ResolveKeys()
+	if err != nil {
+		return nil, err
+	}
+	pc.Base64Keys, pc.KeyDecodingMode = keys.IsBase64Keys, keys.KeyDecodingMode
+
+	pricer, err := builder(pc, keys.EncryptionKey, keys.IntegrityKey)
+	if err != nil {
+		return nil, Errors{pc.errorf(pc.line, "", "cannot build pricer: %s", err)}
+	}
+	return pricer, nil
+}
+
+// buildWithKeys : Returns the pricer built from the keys of its key file,
+// which are handed over without being turned back into strings, so that
+// zeroing the pricer zeroes the only copy.
+func (pc PricerConfig) buildWithKeys(keyBuilder keyBuilder) (pricers.Pricer, error) {
+	keys, err := pc.KeyFile.load(pc)
+	if err != nil {
+		return nil, err
+	}
+	pc.Base64Keys, pc.KeyDecodingMode = keys.Base64Keys, keys.KeyDecodingMode
+
+	pricer, err := keyBuilder(pc, keys.EncryptionKey, keys.IntegrityKey)
+	if err != nil {
+		keys.Zero()
+		return nil, Errors{pc.errorf(pc.line, "", "cannot build pricer: %s", err)}
+	}
+	return pricer, nil
+}
+
+// ResolveKeys : Returns the keys of the pricer read from their sources, along
+// with how they are decoded. Errors are returned as Errors.
+func (pc PricerConfig) ResolveKeys() (doubleclick.KeyConfig, error) {
+	if pc.KeyFile != nil {
+		return pc.KeyFile.resolve(pc)
+	}
+
+	var errs Errors
+	encryptionKey, err := pc.EncryptionKey.Resolve()
+	if err != nil {
+		errs = append(errs, pc.errorf(pc.EncryptionKey.line, "encryption_key", "%s", err))
+	}
+	integrityKey, err := pc.IntegrityKey.Resolve()
+	if err != nil {
+		errs = append(errs, pc.errorf(pc.IntegrityKey.line, "integrity_key", "%s", err))
+	}
+	if len(errs) > 0 {
+		return doubleclick.KeyConfig{}, errs
+	}
+	if err := pc.checkFingerprint(pc.EncryptionKey, encryptionKey); err != nil {
+		errs = append(errs, pc.errorf(pc.EncryptionKey.line, "encryption_key", "%s", err))
+	}
+	if err := pc.checkFingerprint(pc.IntegrityKey, integrityKey); err != nil {
+		errs = append(errs, pc.errorf(pc.IntegrityKey.line, "integrity_key", "%s", err))
+	}
+	if len(errs) > 0 {
+		return doubleclick.KeyConfig{}, errs
+	}
+	return doubleclick.KeyConfig{
+		EncryptionKey:   encryptionKey,
+		IntegrityKey:    integrityKey,
+		IsBase64Keys:    pc.Base64Keys,
+		KeyDecodingMode: pc.KeyDecodingMode,
+	}, nil
+}
+
+// load : Returns the keys held by the key file, opened with its passphrase.
+// Errors are returned as Errors.
+func (kf *KeyFile) load(pc PricerConfig) (keyfile.Keys, error) {
+	passphrase, err := kf.Passphrase.Resolve()
+	if err != nil {
+		return keyfile.Keys{}, Errors{pc.errorf(kf.Passphrase.line, "key_file.passphrase", "%s", err)}
+	}
+	keys, err := keyfile.Load(kf.Path, []byte(passphrase))
+	if err != nil {
+		return keyfile.Keys{}, Errors{pc.errorf(kf.line, "key_file", "%s", err)}
+	}
+	return keys, nil
+}
+
+// resolve : Returns the keys held by the key file, encoded in the form the
+// exchange issued them.
+func (kf *KeyFile) resolve(pc PricerConfig) (doubleclick.KeyConfig, error) {
+	keys, err := kf.load(pc)
+	if err != nil {
+		return doubleclick.KeyConfig{}, err
+	}
+	defer keys.Zero()
+	encryptionKey, integrityKey := keys.Encode()
+	return doubleclick.KeyConfig{
+		EncryptionKey:   encryptionKey,
+		IntegrityKey:    integrityKey,
+		IsBase64Keys:    keys.Base64Keys,
+		KeyDecodingMode: keys.KeyDecodingMode,
+	}, nil
+}
+
+// checkFingerprint : Returns an error if the key doesn't have the fingerprint
+// expected by its source. Keys failing to decode are left to the builder.
+func (pc PricerConfig) checkFingerprint(ks KeySource, key string) error {
+	if ks.Fingerprint == "" {
+		return nil
+	}
+	decoded, err := helpers.ParseKey(key, pc.Base64Keys, pc.KeyDecodingMode)
+	if err != nil {
+		return nil
+	}
+	defer decoded.Zero()
+	if fingerprint := decoded.Fingerprint(); fingerprint != ks.Fingerprint {
+		return fmt.Errorf("key fingerprint is %s instead of %s", fingerprint, ks.Fingerprint)
+	}
+	return nil
+}
+
+func (pc PricerConfig) errorf(line int, field string, format string, args ...interface{}) *Error {
+	path := "pricers." + pc.ID
+	if field != "" {
+		path += "." + field
+	}
+	return &Error{Line: line, Path: path, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+)
+
+func TestLoad(t *testing.T) {
+	// Setup:
+	t.Setenv("PRICERS_TEST_INTEGRITY_KEY", "vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U")
+
+	for _, path := range []string{"testdata/pricers.yaml", "testdata/pricers.json"} {
+		// Execute:
+		set, err := Load(path)
+
+		// Verify:
+		assert.Nil(t, err, "Error loading %s : %s", path, err)
+		assert.Equal(t, []string{"adx", "hexa-exchange"}, set.IDs())
+
+		for _, id := range set.IDs() {
+			result, err := set[id].Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+			assert.Nil(t, err, "Decryption failed. Error : %s", err)
+			assert.InDelta(t, 1.354, result, 0.001)
+		}
+
+		// Limits are applied
+		_, err = set["hexa-exchange"].Decrypt("L91lB6giyIXh2o4CeUf0F7sCXozKWRXAUeMUfg")
+		assert.ErrorIs(t, err, doubleclick.ErrOutOfRange)
+	}
+}
+
+func TestBuildTokenCodecs(t *testing.T) {
+	// Setup:
+	cfg, err := Parse([]byte(`version: 1
+pricers:
+  hex:
+    protocol: doubleclick
+    encryption_key: ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU
+    integrity_key: vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U
+    base64_keys: true
+    token_codec: hex
+  lenient:
+    protocol: doubleclick
+    encryption_key: ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU
+    integrity_key: vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U
+    base64_keys: true
+    lenient_decoding: true
+`))
+	assert.Nil(t, err)
+
+	// Execute:
+	set, err := cfg.Build()
+	assert.Nil(t, err)
+	encrypted, encryptErr := set["hex"].Encrypt("", 1.354)
+	price, decryptErr := set["lenient"].Decrypt(encrypted)
+
+	// Verify:
+	assert.NoError(t, encryptErr)
+	assert.Len(t, encrypted, 56)
+	assert.NoError(t, decryptErr)
+	assert.InDelta(t, 1.354, price, 0.001)
+}
+
+func TestBuildReportsKeySourceErrors(t *testing.T) {
+	// Setup:
+	cfg, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: {env: PRICERS_TEST_UNSET}
+    integrity_key: {file: testdata/missing.txt}
+  hexa:
+    protocol: doubleclick
+    encryption_key: zz
+    integrity_key: zz
+    key_decoding_mode: hexa
+`))
+	assert.Nil(t, err)
+
+	// Execute:
+	_, err = cfg.Build()
+
+	// Verify:
+	assert.Equal(t, `line 5: pricers.adx.encryption_key: environment variable "PRICERS_TEST_UNSET" is not set
+line 6: pricers.adx.integrity_key: open testdata/missing.txt: no such file or directory
+line 7: pricers.hexa: cannot build pricer: encoding/hex: invalid byte: U+007A 'z'`, err.Error())
+}
+
+func TestBuildChecksKeyFingerprints(t *testing.T) {
+	// Setup:
+	cfg, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: {value: 652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135, fingerprint: 06ae61}
+    integrity_key: {value: bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5, fingerprint: 06ae61}
+    key_decoding_mode: hexa
+`))
+	assert.Nil(t, err)
+
+	// Execute:
+	_, err = cfg.Build()
+
+	// Verify:
+	assert.Equal(t, `line 6: pricers.adx.integrity_key: key fingerprint is 8aa8d3 instead of 06ae61`, err.Error())
+}
+
+func TestBuildWithKeyFile(t *testing.T) {
+	// Setup:
+	t.Setenv("PRICERS_TEST_PASSPHRASE", "correct horse battery staple")
+	cfg, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    key_file: {path: testdata/adx.keys, passphrase: {env: PRICERS_TEST_PASSPHRASE}}
+    key_decoding_mode: hexa
+`))
+	assert.Nil(t, err)
+
+	// Execute:
+	set, err := cfg.Build()
+
+	// Verify:
+	assert.Nil(t, err)
+	price, err := set["adx"].Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.354, price, 0.000001)
+}
+
+func TestLoadResolvesRelativeKeyFile(t *testing.T) {
+	// Setup:
+	t.Setenv("PRICERS_TEST_PASSPHRASE", "correct horse battery staple")
+	dir := t.TempDir()
+	keys, err := os.ReadFile("testdata/adx.keys")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "adx.keys"), keys, 0600))
+	path := filepath.Join(dir, "pricers.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    key_file: {path: adx.keys, passphrase: {env: PRICERS_TEST_PASSPHRASE}}
+    key_decoding_mode: hexa
+`), 0600))
+
+	// Execute:
+	cfg, parseErr := ParseFile(path)
+	set, err := Load(path)
+
+	// Verify:
+	assert.Nil(t, parseErr)
+	assert.Equal(t, filepath.Join(dir, "adx.keys"), cfg.Pricers[0].KeyFile.Path)
+	assert.Nil(t, err)
+	price, err := set["adx"].Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.354, price, 0.000001)
+}
+
+func TestBuildReportsKeyFileErrors(t *testing.T) {
+	// Setup:
+	cfg, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    key_file: {path: testdata/adx.keys, passphrase: wrong}
+  openx:
+    protocol: doubleclick
+    key_file:
+      path: testdata/adx.keys
+      passphrase: {env: PRICERS_TEST_UNSET}
+`))
+	assert.Nil(t, err)
+
+	// Execute:
+	_, err = cfg.Build()
+
+	// Verify:
+	assert.Equal(t, `line 5: pricers.adx.key_file: testdata/adx.keys: Wrong passphrase or tampered key file
+line 10: pricers.openx.key_file.passphrase: environment variable "PRICERS_TEST_UNSET" is not set`, err.Error())
+}
+
+func TestBuildRestrictsPricersToRole(t *testing.T) {
+	for _, role := range []Role{RoleDecrypt, RoleEncrypt, RoleBoth} {
+		t.Run(string(role), func(t *testing.T) {
+			// Setup:
+			cfg, err := Parse([]byte(`version: 1
+role: ` + string(role) + `
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU
+    integrity_key: vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U
+    base64_keys: true
+`))
+			assert.Nil(t, err)
+			set, err := cfg.Build()
+			assert.Nil(t, err)
+
+			// Execute:
+			_, encryptErr := set["adx"].Encrypt("", 1.354)
+			_, decryptErr := set["adx"].Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+
+			// Verify:
+			if role == RoleDecrypt {
+				assert.ErrorIs(t, encryptErr, ErrForbiddenByRole)
+			} else {
+				assert.NoError(t, encryptErr)
+			}
+			if role == RoleEncrypt {
+				assert.ErrorIs(t, decryptErr, ErrForbiddenByRole)
+			} else {
+				assert.NoError(t, decryptErr)
+			}
+		})
+	}
+}
+
+func TestSchemaIsValidJSON(t *testing.T) {
+	// Execute:
+	var schema map[string]interface{}
+	err := json.Unmarshal(Schema, &schema)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "Pricers configuration", schema["title"])
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Error is a configuration error located in the file.
+type Error struct {
+	// Line is the 1-based line the error was found at, 0 if unknown.
+	Line int
+	// Path is the dotted path of the faulty field, e.g. pricers.adx.scale_factor.
+	Path string
+	Msg  string
+}
+
+// Error : Returns the Error message.
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		b.WriteString("line ")
+		b.WriteString(strconv.Itoa(e.Line))
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// Errors holds every error found in a configuration.
+type Errors []*Error
+
+// Error : Returns the errors messages, one per line.
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
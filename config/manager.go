@@ -0,0 +1,290 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/benjaminch/pricers"
+)
+
+// Source provides the configuration a Manager builds pricers from.
+type Source interface {
+	Read() ([]byte, error)
+}
+
+// FileSource reads the configuration from a YAML or JSON file.
+type FileSource string
+
+// Read : Returns the file content.
+func (fs FileSource) Read() ([]byte, error) {
+	return os.ReadFile(string(fs))
+}
+
+// BytesSource is a Source holding the configuration itself.
+type BytesSource []byte
+
+// Read : Returns the configuration.
+func (bs BytesSource) Read() ([]byte, error) {
+	return bs, nil
+}
+
+// ReloadEvent describes a configuration reload.
+type ReloadEvent struct {
+	Time time.Time
+	// IDs are the exchange IDs of the set in place after the reload.
+	IDs []string
+	// Err is the reason the new configuration was rejected, nil on success.
+	Err error
+}
+
+// DefaultGracePeriod is the time replaced pricers are kept for before
+// their keys are zeroed, unless set with WithGracePeriod.
+const DefaultGracePeriod = time.Minute
+
+// Manager owns the pricers built from a configuration source and swaps
+// them atomically when the configuration, or a key it refers to, changes.
+// Getting a pricer never blocks, in-flight calls keep using the pricer
+// they got while a reload happens. Replaced pricers are retired, their keys
+// being zeroed, once the grace period elapsed: pricers must not be kept
+// longer, but got from the Manager again.
+// A Manager is safe for concurrent use.
+type Manager struct {
+	source      Source
+	current     atomic.Pointer[Set]
+	gracePeriod time.Duration
+
+	// mu serializes reloads.
+	mu sync.Mutex
+	// digest is the inputDigest of the last reload attempt, successful or not.
+	digest [sha256.Size]byte
+
+	hooksMu sync.RWMutex
+	hooks   []func(ReloadEvent)
+
+	// retiring holds the replaced sets whose grace period didn't elapse.
+	retiringMu sync.Mutex
+	retiring   map[*Set]*time.Timer
+}
+
+// ManagerOption configures a Manager.
+type ManagerOption func(*Manager)
+
+// WithGracePeriod sets the time replaced pricers are kept for before their
+// keys are zeroed, DefaultGracePeriod by default. It should outlast the
+// in-flight calls, a zero grace period retiring pricers as soon as replaced.
+func WithGracePeriod(gracePeriod time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.gracePeriod = gracePeriod
+	}
+}
+
+// NewManager returns a Manager whose pricers are built from source.
+// The initial configuration must be valid.
+func NewManager(source Source, options ...ManagerOption) (*Manager, error) {
+	m := &Manager{source: source, gracePeriod: DefaultGracePeriod, retiring: map[*Set]*time.Timer{}}
+	for _, option := range options {
+		option(m)
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close retires every pricer, current or replaced, without waiting for
+// grace periods. The Manager must not be used afterwards.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retiringMu.Lock()
+	for set, timer := range m.retiring {
+		timer.Stop()
+		set.Zero()
+		delete(m.retiring, set)
+	}
+	m.retiringMu.Unlock()
+	if set := m.current.Load(); set != nil {
+		set.Zero()
+	}
+}
+
+// Set : Returns the current set of pricers. It must not be modified.
+func (m *Manager) Set() Set {
+	return *m.current.Load()
+}
+
+// Pricer : Returns the current pricer of an exchange.
+func (m *Manager) Pricer(id string) (pricers.Pricer, bool) {
+	pricer, ok := m.Set()[id]
+	return pricer, ok
+}
+
+// OnReload registers a hook called after every reload attempt, successful or not.
+// Hooks are called without any lock held, and may call Reload.
+func (m *Manager) OnReload(hook func(ReloadEvent)) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Reload reads, validates and builds the configuration, swapping the
+// current set of pricers on success. On error, the current set is kept.
+func (m *Manager) Reload() error {
+	event, _ := m.reload(true)
+	m.notify(event)
+	return event.Err
+}
+
+// Watch polls the source every interval until ctx is done, reloading
+// when its content, or the content of a key source it refers to, changes.
+// A failed reload is reported once, until the inputs change again.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reloadIfChanged()
+		}
+	}
+}
+
+func (m *Manager) reloadIfChanged() {
+	if event, ok := m.reload(false); ok {
+		m.notify(event)
+	}
+}
+
+// reload reloads the configuration unless force is false and the inputs
+// didn't change since the last attempt, returning the reload event and
+// whether a reload was attempted.
+func (m *Manager) reload(force bool) (ReloadEvent, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.source.Read()
+	var digest [sha256.Size]byte
+	if err != nil {
+		digest = sha256.Sum256([]byte(err.Error()))
+	} else {
+		digest = inputDigest(data, m.dir())
+	}
+	if !force && digest == m.digest {
+		return ReloadEvent{}, false
+	}
+	m.digest = digest
+
+	if err == nil {
+		err = m.swap(data)
+	}
+	event := ReloadEvent{Time: time.Now(), Err: err}
+	if set := m.current.Load(); set != nil {
+		event.IDs = set.IDs()
+	}
+	return event, true
+}
+
+// swap builds the pricers described by data and makes them current.
+// m.mu must be held.
+func (m *Manager) swap(data []byte) error {
+	cfg, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	cfg.relativeTo(m.dir())
+	set, err := cfg.Build()
+	if err != nil {
+		return err
+	}
+
+	if previous := m.current.Swap(&set); previous != nil {
+		m.retire(previous)
+	}
+	return nil
+}
+
+// retire zeroes a replaced set once the grace period elapsed.
+func (m *Manager) retire(set *Set) {
+	if m.gracePeriod <= 0 {
+		set.Zero()
+		return
+	}
+	m.retiringMu.Lock()
+	defer m.retiringMu.Unlock()
+	m.retiring[set] = time.AfterFunc(m.gracePeriod, func() {
+		m.retiringMu.Lock()
+		_, ok := m.retiring[set]
+		delete(m.retiring, set)
+		m.retiringMu.Unlock()
+		if ok {
+			set.Zero()
+		}
+	})
+}
+
+// dir : Returns the directory relative key_file paths are relative to,
+// the one of the configuration file, empty for other sources.
+func (m *Manager) dir() string {
+	if fs, ok := m.source.(FileSource); ok {
+		return filepath.Dir(string(fs))
+	}
+	return ""
+}
+
+// notify calls the hooks, after m.mu was released so that hooks can reload.
+func (m *Manager) notify(event ReloadEvent) {
+	m.hooksMu.RLock()
+	hooks := make([]func(ReloadEvent), len(m.hooks))
+	copy(hooks, m.hooks)
+	m.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
+
+// inputDigest : Returns a digest of a configuration and of the keys it
+// refers to, so that keys rotated in their env, file or key_file sources
+// are reloaded. Keys are only hashed, never kept. Relative key_file paths
+// are relative to dir.
+func inputDigest(data []byte, dir string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(data)
+	if cfg, err := Parse(data); err == nil {
+		cfg.relativeTo(dir)
+		for _, pc := range cfg.Pricers {
+			if pc.KeyFile != nil {
+				writeSource(h, KeySource{File: pc.KeyFile.Path})
+				writeSource(h, pc.KeyFile.Passphrase)
+				continue
+			}
+			writeSource(h, pc.EncryptionKey)
+			writeSource(h, pc.IntegrityKey)
+		}
+	}
+
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+	return digest
+}
+
+// writeSource writes the value of a key source to h, or its resolution error.
+// Values are prefixed with their length, so that they can't be confused.
+func writeSource(h hash.Hash, ks KeySource) {
+	value, err := ks.Resolve()
+	if err != nil {
+		value = "error: " + err.Error()
+	}
+	fmt.Fprintf(h, "%d:%s", len(value), value)
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+)
+
+const googleKeysConfig = `version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU
+    integrity_key: vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U
+    base64_keys: true
+`
+
+const rotatedKeysConfig = `version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: 6356770B3C111C07F778AFD69F16643E9110090FD4C479D91181EED2523788F1
+    integrity_key: 3588BF6D387E8AEAD4EEC66798255369AF47BFD48B056E8934CEFEF3609C469E
+  openx:
+    protocol: doubleclick
+    encryption_key: ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU
+    integrity_key: vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U
+    base64_keys: true
+`
+
+func writeConfig(t *testing.T, path string, content string) {
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestManagerReload(t *testing.T) {
+	// Setup:
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	writeConfig(t, path, googleKeysConfig)
+	manager, err := NewManager(FileSource(path))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+
+	var events []ReloadEvent
+	manager.OnReload(func(event ReloadEvent) { events = append(events, event) })
+	previous, ok := manager.Pricer("adx")
+	assert.True(t, ok)
+
+	// Execute:
+	writeConfig(t, path, rotatedKeysConfig)
+	err = manager.Reload()
+
+	// Verify:
+	assert.Nil(t, err, "Error reloading : ", err)
+	assert.Equal(t, []string{"adx", "openx"}, manager.Set().IDs())
+	assert.Len(t, events, 1)
+	assert.Nil(t, events[0].Err)
+	assert.Equal(t, []string{"adx", "openx"}, events[0].IDs)
+
+	current, _ := manager.Pricer("adx")
+	result, err := current.Decrypt("u7iq5XwQTNpAyThDrV5tuJXw-Y_IXQgkMA3RFA")
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.465, result, 0.001)
+
+	// Pricers got before the reload keep working
+	result, err = previous.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+}
+
+func TestManagerKeepsLastGoodSet(t *testing.T) {
+	// Setup:
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	writeConfig(t, path, googleKeysConfig)
+	manager, err := NewManager(FileSource(path))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+
+	var events []ReloadEvent
+	manager.OnReload(func(event ReloadEvent) { events = append(events, event) })
+
+	// Execute:
+	writeConfig(t, path, "version: 1\npricers:\n  adx:\n    protocol: doubleclick\n")
+	err = manager.Reload()
+
+	// Verify:
+	assert.NotNil(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, err, events[0].Err)
+	assert.Equal(t, []string{"adx"}, events[0].IDs)
+
+	pricer, _ := manager.Pricer("adx")
+	result, err := pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+}
+
+func TestNewManagerRejectsInvalidConfig(t *testing.T) {
+	// Execute:
+	manager, err := NewManager(BytesSource("version: 1\n"))
+
+	// Verify:
+	assert.Nil(t, manager)
+	assert.EqualError(t, err, "line 1: pricers: is required")
+}
+
+func TestManagerWatch(t *testing.T) {
+	// Setup:
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	writeConfig(t, path, googleKeysConfig)
+	manager, err := NewManager(FileSource(path))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+
+	reloaded := make(chan ReloadEvent, 1)
+	manager.OnReload(func(event ReloadEvent) { reloaded <- event })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Watch(ctx, 5*time.Millisecond)
+
+	// Concurrent decryptions while reloading
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pricer, _ := manager.Pricer("adx")
+				_, _ = pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+			}
+		}()
+	}
+
+	// Execute:
+	writeConfig(t, path, rotatedKeysConfig)
+
+	// Verify:
+	// The file may be read while partially written, hence rejected once.
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case event := <-reloaded:
+			done = event.Err == nil
+		case <-timeout:
+			t.Fatal("Configuration was not reloaded")
+		}
+	}
+	assert.Equal(t, []string{"adx", "openx"}, manager.Set().IDs())
+	wg.Wait()
+}
+
+func TestManagerWatchRotatedKeyFile(t *testing.T) {
+	// Setup:
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pricers.yaml")
+	keyPath := filepath.Join(dir, "adx_encryption")
+	writeConfig(t, keyPath, "ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU")
+	writeConfig(t, path, `version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: {file: `+keyPath+`}
+    integrity_key: vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U
+    base64_keys: true
+`)
+	manager, err := NewManager(FileSource(path))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+
+	var events []ReloadEvent
+	manager.OnReload(func(event ReloadEvent) { events = append(events, event) })
+
+	// Execute:
+	manager.reloadIfChanged()
+	unchanged := len(events)
+	writeConfig(t, keyPath, "vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U")
+	manager.reloadIfChanged()
+
+	// Verify:
+	assert.Equal(t, 0, unchanged)
+	assert.Len(t, events, 1)
+	assert.Nil(t, events[0].Err)
+	pricer, _ := manager.Pricer("adx")
+	_, err = pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.ErrorIs(t, err, doubleclick.ErrWrongSignature)
+}
+
+func TestManagerReportsFailuresOnce(t *testing.T) {
+	// Setup:
+	path := filepath.Join(t.TempDir(), "pricers.yaml")
+	writeConfig(t, path, googleKeysConfig)
+	manager, err := NewManager(FileSource(path))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+
+	var events []ReloadEvent
+	manager.OnReload(func(event ReloadEvent) { events = append(events, event) })
+
+	// Execute:
+	writeConfig(t, path, "version: 1\n")
+	for i := 0; i < 3; i++ {
+		manager.reloadIfChanged()
+	}
+	writeConfig(t, path, "version: 2\n")
+	manager.reloadIfChanged()
+	writeConfig(t, path, googleKeysConfig)
+	manager.reloadIfChanged()
+
+	// Verify:
+	assert.Len(t, events, 3)
+	assert.EqualError(t, events[0].Err, "line 1: pricers: is required")
+	assert.NotNil(t, events[1].Err)
+	assert.Nil(t, events[2].Err)
+}
+
+func TestManagerHookMayReload(t *testing.T) {
+	// Setup:
+	manager, err := NewManager(BytesSource(googleKeysConfig))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+
+	reloads := 0
+	manager.OnReload(func(event ReloadEvent) {
+		reloads++
+		if reloads == 1 {
+			assert.Nil(t, manager.Reload())
+		}
+	})
+
+	// Execute:
+	err = manager.Reload()
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 2, reloads)
+}
+
+func TestManagerRetiresReplacedPricers(t *testing.T) {
+	// Setup:
+	manager, err := NewManager(BytesSource("role: decrypt\n"+googleKeysConfig), WithGracePeriod(0))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+	previous, _ := manager.Pricer("adx")
+	_, err = previous.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err)
+
+	// Execute:
+	err = manager.Reload()
+
+	// Verify:
+	assert.Nil(t, err)
+	_, err = previous.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.ErrorIs(t, err, doubleclick.ErrWrongSignature)
+	current, _ := manager.Pricer("adx")
+	result, err := current.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.354, result, 0.001)
+}
+
+func TestManagerGracePeriod(t *testing.T) {
+	// Setup:
+	manager, err := NewManager(BytesSource(googleKeysConfig), WithGracePeriod(10*time.Millisecond))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+	previous, _ := manager.Pricer("adx")
+
+	// Execute:
+	err = manager.Reload()
+
+	// Verify:
+	assert.Nil(t, err)
+	_, err = previous.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	assert.Nil(t, err, "Pricers are kept during the grace period")
+	assert.Eventually(t, func() bool {
+		_, err := previous.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestManagerClose(t *testing.T) {
+	// Setup:
+	manager, err := NewManager(BytesSource("role: encrypt\n" + googleKeysConfig))
+	assert.Nil(t, err, "Error creating new Manager : ", err)
+	replaced, _ := manager.Pricer("adx")
+	assert.Nil(t, manager.Reload())
+	current, _ := manager.Pricer("adx")
+	encrypted, err := current.Encrypt("", 1.354)
+	assert.Nil(t, err)
+	assert.Equal(t, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", encrypted)
+
+	// Execute:
+	manager.Close()
+
+	// Verify:
+	for _, pricer := range []interface {
+		Encrypt(string, float64) (string, error)
+	}{replaced, current} {
+		encrypted, err := pricer.Encrypt("", 1.354)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", encrypted)
+	}
+}
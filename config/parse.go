@@ -0,0 +1,374 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// Default values of optional pricer fields.
+const (
+	DefaultKeyDecodingMode = helpers.Utf8
+	DefaultScaleFactor     = 1000000
+	DefaultTokenCodec      = "base64url"
+)
+
+// Parse parses and validates a YAML or JSON configuration.
+// The whole configuration is validated, errors being returned all together
+// as Errors, each one carrying the line it was found at.
+// Keys are not resolved, see Config.Build.
+func Parse(data []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, Errors{syntaxError(err)}
+	}
+	if len(root.Content) == 0 {
+		return nil, Errors{{Msg: "configuration is empty"}}
+	}
+
+	p := &parser{}
+	cfg := p.config(root.Content[0])
+	if len(p.errs) > 0 {
+		sort.SliceStable(p.errs, func(i, j int) bool { return p.errs[i].Line < p.errs[j].Line })
+		return nil, p.errs
+	}
+	return cfg, nil
+}
+
+// syntaxError converts a YAML error, located by the YAML parser, to an Error.
+func syntaxError(err error) *Error {
+	var line int
+	var msg string
+	if _, scanErr := fmt.Sscanf(err.Error(), "yaml: line %d: ", &line); scanErr == nil {
+		msg = err.Error()[len(fmt.Sprintf("yaml: line %d: ", line)):]
+	} else {
+		msg = err.Error()
+	}
+	return &Error{Line: line, Msg: msg}
+}
+
+// parser walks the YAML document, collecting validation errors.
+type parser struct {
+	errs Errors
+}
+
+func (p *parser) errorf(node *yaml.Node, path string, format string, args ...interface{}) {
+	p.errs = append(p.errs, &Error{Line: node.Line, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// fields calls fn for every key of a mapping node, reporting unknown and duplicate keys.
+func (p *parser) fields(node *yaml.Node, path string, known []string, fn func(key string, value *yaml.Node)) bool {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, path, "should be a mapping")
+		return false
+	}
+	seen := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if !contains(known, key.Value) {
+			p.errorf(key, join(path, key.Value), "unknown field")
+			continue
+		}
+		if first, ok := seen[key.Value]; ok {
+			p.errorf(key, join(path, key.Value), "is already defined at line %d", first.Line)
+			continue
+		}
+		seen[key.Value] = key
+		fn(key.Value, value)
+	}
+	return true
+}
+
+func (p *parser) config(node *yaml.Node) *Config {
+	cfg := &Config{Role: DefaultRole}
+	var pricersNode *yaml.Node
+
+	if !p.fields(node, "", []string{"version", "role", "pricers"}, func(key string, value *yaml.Node) {
+		switch key {
+		case "role":
+			role, err := ParseRole(p.string(value, key))
+			if err != nil {
+				p.errorf(value, key, "%s, should be one of [%s %s %s]", err, RoleBoth, RoleDecrypt, RoleEncrypt)
+			}
+			cfg.Role = role
+		case "version":
+			cfg.Version = p.int(value, key)
+			if cfg.Version != Version {
+				p.errorf(value, key, "unsupported version %d, should be %d", cfg.Version, Version)
+			}
+		case "pricers":
+			pricersNode = value
+		}
+	}) {
+		return cfg
+	}
+
+	if cfg.Version == 0 {
+		p.errorf(node, "version", "is required")
+	}
+	if pricersNode == nil {
+		p.errorf(node, "pricers", "is required")
+		return cfg
+	}
+	if pricersNode.Kind != yaml.MappingNode {
+		p.errorf(pricersNode, "pricers", "should be a mapping of exchange IDs to pricers")
+		return cfg
+	}
+	if len(pricersNode.Content) == 0 {
+		p.errorf(pricersNode, "pricers", "should describe at least one pricer")
+	}
+
+	ids := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(pricersNode.Content); i += 2 {
+		key, value := pricersNode.Content[i], pricersNode.Content[i+1]
+		if key.Value == "" {
+			p.errorf(key, "pricers", "exchange ID is empty")
+			continue
+		}
+		if first, ok := ids[key.Value]; ok {
+			p.errorf(key, "pricers."+key.Value, "is already defined at line %d", first.Line)
+			continue
+		}
+		ids[key.Value] = key
+		cfg.Pricers = append(cfg.Pricers, p.pricer(key.Value, key, value))
+	}
+	sort.Slice(cfg.Pricers, func(i, j int) bool { return cfg.Pricers[i].ID < cfg.Pricers[j].ID })
+
+	return cfg
+}
+
+func (p *parser) pricer(id string, key *yaml.Node, node *yaml.Node) PricerConfig {
+	path := "pricers." + id
+	pc := PricerConfig{
+		ID:              id,
+		KeyDecodingMode: DefaultKeyDecodingMode,
+		ScaleFactor:     DefaultScaleFactor,
+		TokenCodec:      DefaultTokenCodec,
+		line:            key.Line,
+	}
+	seen := map[string]*yaml.Node{}
+
+	p.fields(node, path, []string{"protocol", "encryption_key", "integrity_key", "key_file", "key_decoding_mode", "base64_keys", "scale_factor", "limits", "token_codec", "lenient_decoding"}, func(field string, value *yaml.Node) {
+		seen[field] = value
+		fieldPath := join(path, field)
+		switch field {
+		case "protocol":
+			pc.Protocol = p.string(value, fieldPath)
+			if _, ok := lookupProtocol(pc.Protocol); !ok && pc.Protocol != "" {
+				p.errorf(value, fieldPath, "unknown protocol %q, should be one of %v", pc.Protocol, Protocols())
+			}
+		case "encryption_key":
+			pc.EncryptionKey = p.keySource(value, fieldPath)
+		case "integrity_key":
+			pc.IntegrityKey = p.keySource(value, fieldPath)
+		case "key_file":
+			pc.KeyFile = p.keyFile(value, fieldPath)
+		case "key_decoding_mode":
+			mode, err := helpers.ParseKeyDecodingMode(p.string(value, fieldPath))
+			if err != nil {
+				p.errorf(value, fieldPath, "%s, should be %q or %q", err, helpers.Utf8, helpers.Hexa)
+			}
+			pc.KeyDecodingMode = mode
+		case "base64_keys":
+			pc.Base64Keys = p.bool(value, fieldPath)
+		case "scale_factor":
+			var ok bool
+			if pc.ScaleFactor, ok = p.float(value, fieldPath); ok && pc.ScaleFactor <= 0 {
+				p.errorf(value, fieldPath, "should be positive")
+			}
+		case "limits":
+			pc.Limits = p.limits(value, fieldPath)
+		case "token_codec":
+			pc.TokenCodec = p.string(value, fieldPath)
+			if _, err := doubleclick.ParseCodec(pc.TokenCodec); err != nil {
+				p.errorf(value, fieldPath, "%s, should be one of %v", err, doubleclick.CodecNames())
+			}
+		case "lenient_decoding":
+			pc.LenientDecoding = p.bool(value, fieldPath)
+		}
+	})
+
+	if seen["protocol"] == nil {
+		p.errorf(key, join(path, "protocol"), "is required")
+	}
+	for _, field := range []string{"encryption_key", "integrity_key"} {
+		if seen["key_file"] == nil && seen[field] == nil {
+			p.errorf(key, join(path, field), "is required")
+		} else if seen["key_file"] != nil && seen[field] != nil {
+			p.errorf(seen[field], join(path, field), "should not be set along with key_file")
+		}
+	}
+
+	return pc
+}
+
+func (p *parser) keyFile(node *yaml.Node, path string) *KeyFile {
+	kf := &KeyFile{line: node.Line}
+	seen := map[string]bool{}
+	if !p.fields(node, path, []string{"path", "passphrase"}, func(key string, value *yaml.Node) {
+		seen[key] = true
+		switch key {
+		case "path":
+			kf.Path = p.string(value, join(path, key))
+			if kf.Path == "" {
+				p.errorf(value, join(path, key), "is empty")
+			}
+		case "passphrase":
+			kf.Passphrase = p.keySource(value, join(path, key))
+			if kf.Passphrase.Fingerprint != "" {
+				p.errorf(value, join(join(path, key), "fingerprint"), "is only supported for keys")
+			}
+		}
+	}) {
+		return kf
+	}
+	for _, required := range []string{"path", "passphrase"} {
+		if !seen[required] {
+			p.errorf(node, join(path, required), "is required")
+		}
+	}
+	return kf
+}
+
+func (p *parser) keySource(node *yaml.Node, path string) KeySource {
+	ks := KeySource{line: node.Line}
+	if node.Kind == yaml.ScalarNode {
+		ks.Value = p.string(node, path)
+		if ks.Value == "" {
+			p.errorf(node, path, "is empty")
+		}
+		return ks
+	}
+
+	set := 0
+	if !p.fields(node, path, []string{"value", "env", "file", "fingerprint"}, func(key string, value *yaml.Node) {
+		v := p.string(value, join(path, key))
+		if v == "" {
+			p.errorf(value, join(path, key), "is empty")
+			return
+		}
+		if key == "fingerprint" {
+			if !isFingerprint(v) {
+				p.errorf(value, join(path, key), "should be 6 lowercase hexa digits")
+			}
+			ks.Fingerprint = v
+			return
+		}
+		set++
+		switch key {
+		case "value":
+			ks.Value = v
+		case "env":
+			ks.Env = v
+		case "file":
+			ks.File = v
+		}
+	}) {
+		return ks
+	}
+	if set != 1 {
+		p.errorf(node, path, "exactly one of value, env or file should be set")
+	}
+	return ks
+}
+
+func isFingerprint(s string) bool {
+	return len(s) == 6 && strings.Trim(s, "0123456789abcdef") == ""
+}
+
+func (p *parser) limits(node *yaml.Node, path string) Limits {
+	var limits Limits
+	p.fields(node, path, []string{"min_price", "max_price"}, func(key string, value *yaml.Node) {
+		v, ok := p.float(value, join(path, key))
+		if ok && v < 0 {
+			p.errorf(value, join(path, key), "should not be negative")
+		}
+		switch key {
+		case "min_price":
+			limits.MinPrice = v
+		case "max_price":
+			limits.MaxPrice = v
+		}
+	})
+	if limits.MaxPrice != 0 && limits.MinPrice > limits.MaxPrice {
+		p.errorf(node, path, "min_price %g is greater than max_price %g", limits.MinPrice, limits.MaxPrice)
+	}
+	return limits
+}
+
+func (p *parser) scalar(node *yaml.Node, path string, kind string) bool {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, path, "should be a %s", kind)
+		return false
+	}
+	return true
+}
+
+func (p *parser) string(node *yaml.Node, path string) string {
+	if !p.scalar(node, path, "string") {
+		return ""
+	}
+	return node.Value
+}
+
+func (p *parser) int(node *yaml.Node, path string) int {
+	if !p.scalar(node, path, "integer") {
+		return 0
+	}
+	v, err := strconv.Atoi(node.Value)
+	if err != nil {
+		p.errorf(node, path, "should be an integer")
+	}
+	return v
+}
+
+// float : Returns the number held by node, and false if it is not a finite number.
+func (p *parser) float(node *yaml.Node, path string) (float64, bool) {
+	if !p.scalar(node, path, "number") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(node.Value, 64)
+	if err != nil {
+		p.errorf(node, path, "should be a number")
+		return 0, false
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		p.errorf(node, path, "should be a finite number")
+		return 0, false
+	}
+	return v, true
+}
+
+func (p *parser) bool(node *yaml.Node, path string) bool {
+	if !p.scalar(node, path, "boolean") {
+		return false
+	}
+	v, err := strconv.ParseBool(node.Value)
+	if err != nil {
+		p.errorf(node, path, "should be a boolean")
+	}
+	return v
+}
+
+func join(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func TestParseDefaults(t *testing.T) {
+	// Execute:
+	cfg, err := Parse([]byte(`{"version": 1, "pricers": {"adx": {"protocol": "doubleclick", "encryption_key": "a", "integrity_key": "b"}}}`))
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cfg.Version)
+	assert.Len(t, cfg.Pricers, 1)
+	assert.Equal(t, "adx", cfg.Pricers[0].ID)
+	assert.Equal(t, helpers.Utf8, cfg.Pricers[0].KeyDecodingMode)
+	assert.Equal(t, float64(DefaultScaleFactor), cfg.Pricers[0].ScaleFactor)
+	assert.False(t, cfg.Pricers[0].Base64Keys)
+	assert.Equal(t, "a", cfg.Pricers[0].EncryptionKey.Value)
+	assert.Equal(t, DefaultTokenCodec, cfg.Pricers[0].TokenCodec)
+	assert.False(t, cfg.Pricers[0].LenientDecoding)
+	assert.Equal(t, RoleBoth, cfg.Role)
+}
+
+func TestParseReportsEveryErrorWithLine(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte(`version: 2
+role: admin
+pricers:
+  adx:
+    protocol: blowfish
+    encryption_key: {env: A, file: b}
+    key_decoding_mode: base32
+    base64_keys: maybe
+    scale_factor: 0
+    limits: {min_price: 10, max_price: 1}
+    token_codec: base32
+    colour: blue
+    integrity_key: {env: B, fingerprint: 8AA8D3}
+`))
+
+	// Verify:
+	assert.Equal(t, `line 1: version: unsupported version 2, should be 1
+line 2: role: unknown role "admin", should be one of [both decrypt encrypt]
+line 5: pricers.adx.protocol: unknown protocol "blowfish", should be one of [doubleclick]
+line 6: pricers.adx.encryption_key: exactly one of value, env or file should be set
+line 7: pricers.adx.key_decoding_mode: input doesn't match to any key decoding mode, should be "utf-8" or "hexa"
+line 8: pricers.adx.base64_keys: should be a boolean
+line 9: pricers.adx.scale_factor: should be positive
+line 10: pricers.adx.limits: min_price 10 is greater than max_price 1
+line 11: pricers.adx.token_codec: unknown codec "base32", should be one of [base64 base64url base64url-padded hex]
+line 12: pricers.adx.colour: unknown field
+line 13: pricers.adx.integrity_key.fingerprint: should be 6 lowercase hexa digits`, err.Error())
+	assert.Len(t, err.(Errors), 11)
+}
+
+func TestParseKeyFileErrors(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: a
+    key_file: {passphrase: {env: A, fingerprint: 06ae61}}
+`))
+
+	// Verify:
+	assert.Equal(t, `line 5: pricers.adx.encryption_key: should not be set along with key_file
+line 6: pricers.adx.key_file.passphrase.fingerprint: is only supported for keys
+line 6: pricers.adx.key_file.path: is required`, err.Error())
+}
+
+func TestParseRejectsDuplicateExchangeIDs(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: a
+    integrity_key: b
+  adx:
+    protocol: doubleclick
+    encryption_key: c
+    integrity_key: d
+`))
+
+	// Verify:
+	assert.EqualError(t, err, "line 7: pricers.adx: is already defined at line 3")
+}
+
+func TestParseRejectsDuplicateFields(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte(`version: 1
+version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: {env: ADX_ENCRYPTION_KEY, env: OTHER_KEY}
+    integrity_key: b
+    integrity_key: c
+`))
+
+	// Verify:
+	assert.EqualError(t, err, "line 2: version: is already defined at line 1\n"+
+		"line 6: pricers.adx.encryption_key.env: is already defined at line 6\n"+
+		"line 8: pricers.adx.integrity_key: is already defined at line 7")
+}
+
+func TestParseRejectsNonFiniteNumbers(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte(`version: 1
+pricers:
+  adx:
+    protocol: doubleclick
+    encryption_key: a
+    integrity_key: b
+    scale_factor: NaN
+    limits: {max_price: .inf}
+`))
+
+	// Verify:
+	assert.Equal(t, `line 7: pricers.adx.scale_factor: should be a finite number
+line 8: pricers.adx.limits.max_price: should be a number`, err.Error())
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte("version: 1\npricers:\n  adx: [\n"))
+
+	// Verify:
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, err.(Errors)[0].Line)
+}
+
+func TestParseEmpty(t *testing.T) {
+	// Execute:
+	_, err := Parse([]byte(""))
+
+	// Verify:
+	assert.EqualError(t, err, "configuration is empty")
+}
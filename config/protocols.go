@@ -0,0 +1,98 @@
+package config
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// Builder builds a pricer from its configuration and resolved keys.
+type Builder func(pc PricerConfig, encryptionKey string, integrityKey string) (pricers.Pricer, error)
+
+var (
+	protocolsMu sync.RWMutex
+	protocols   = map[string]Builder{
+		doubleclick.Protocol: buildDoubleClickPricer,
+	}
+)
+
+// keyBuilder builds a pricer from its configuration and decoded keys, which
+// the pricer takes ownership of. Protocols without one are given the keys
+// of key files as strings.
+type keyBuilder func(pc PricerConfig, encryptionKey *helpers.Key, integrityKey *helpers.Key) (pricers.Pricer, error)
+
+var keyBuilders = map[string]keyBuilder{
+	doubleclick.Protocol: buildDoubleClickPricerWithKeys,
+}
+
+// Register makes a protocol available to configuration files.
+// It is meant to be called from an init function, to plug in-house pricers.
+func Register(protocol string, builder Builder) {
+	protocolsMu.Lock()
+	defer protocolsMu.Unlock()
+	protocols[protocol] = builder
+}
+
+// Protocols : Returns the sorted names of the supported protocols.
+func Protocols() []string {
+	protocolsMu.RLock()
+	defer protocolsMu.RUnlock()
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupProtocol(protocol string) (Builder, bool) {
+	protocolsMu.RLock()
+	defer protocolsMu.RUnlock()
+	builder, ok := protocols[protocol]
+	return builder, ok
+}
+
+func buildDoubleClickPricer(pc PricerConfig, encryptionKey string, integrityKey string) (pricers.Pricer, error) {
+	options, err := pc.DoubleClickOptions()
+	if err != nil {
+		return nil, err
+	}
+	return doubleclick.NewDoubleClickPricer(
+		encryptionKey,
+		integrityKey,
+		pc.Base64Keys,
+		pc.KeyDecodingMode,
+		pc.ScaleFactor,
+		false,
+		options...,
+	)
+}
+
+func buildDoubleClickPricerWithKeys(pc PricerConfig, encryptionKey *helpers.Key, integrityKey *helpers.Key) (pricers.Pricer, error) {
+	options, err := pc.DoubleClickOptions()
+	if err != nil {
+		return nil, err
+	}
+	return doubleclick.NewDoubleClickPricerWithKeys(encryptionKey, integrityKey, pc.ScaleFactor, false, options...)
+}
+
+// DoubleClickOptions : Returns the options of the doubleclick pricer described
+// by the configuration, e.g. to be given to doubleclick.Diagnose.
+func (pc PricerConfig) DoubleClickOptions() ([]doubleclick.Option, error) {
+	codec, err := doubleclick.ParseCodec(pc.TokenCodec)
+	if err != nil {
+		return nil, err
+	}
+	options := []doubleclick.Option{
+		doubleclick.WithExchange(pc.ID),
+		doubleclick.WithPriceLimits(pc.Limits.MinPrice, pc.Limits.MaxPrice),
+		doubleclick.WithCodec(codec),
+	}
+	if pc.LenientDecoding {
+		options = append(options, doubleclick.WithLenientDecoding())
+	}
+	return options, nil
+}
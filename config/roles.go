@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/benjaminch/pricers"
+)
+
+// Role restricts what the pricers of a configuration can do, so that e.g.
+// the configuration of bidders can forbid minting encrypted prices.
+type Role string
+
+const (
+	// RoleBoth pricers can encrypt and decrypt prices. It is the default.
+	RoleBoth Role = "both"
+	// RoleDecrypt pricers can only decrypt prices, as bidders do.
+	RoleDecrypt Role = "decrypt"
+	// RoleEncrypt pricers can only encrypt prices, as exchanges do.
+	RoleEncrypt Role = "encrypt"
+)
+
+// DefaultRole is the role of configurations not setting one.
+const DefaultRole = RoleBoth
+
+// ErrForbiddenByRole is returned by the pricers of a configuration for the
+// operations its role forbids.
+var ErrForbiddenByRole = errors.New("Operation forbidden by the configured role")
+
+// ParseRole : Parses a Role from string.
+func ParseRole(input string) (Role, error) {
+	switch role := Role(input); role {
+	case RoleBoth, RoleDecrypt, RoleEncrypt:
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q", input)
+}
+
+// restrict : Returns the pricer restricted to the role.
+func (r Role) restrict(pricer pricers.Pricer) pricers.Pricer {
+	switch r {
+	case RoleDecrypt:
+		return decryptOnly{decrypter: pricer}
+	case RoleEncrypt:
+		return encryptOnly{encrypter: pricer}
+	}
+	return pricer
+}
+
+// decryptOnly is a pricer whose Encrypt fails with ErrForbiddenByRole.
+type decryptOnly struct {
+	decrypter pricers.Decrypter
+}
+
+func (p decryptOnly) Encrypt(seed string, price float64) (string, error) {
+	return "", ErrForbiddenByRole
+}
+
+func (p decryptOnly) Decrypt(encryptedPrice string) (float64, error) {
+	return p.decrypter.Decrypt(encryptedPrice)
+}
+
+// TokenCodec : Returns the codec the wrapped pricer decodes a token with,
+// empty if it doesn't tell it.
+func (p decryptOnly) TokenCodec(encryptedPrice string) string {
+	if reporter, ok := p.decrypter.(interface{ TokenCodec(string) string }); ok {
+		return reporter.TokenCodec(encryptedPrice)
+	}
+	return ""
+}
+
+// Exchange : Returns the exchange name of the wrapped pricer, empty if it doesn't tell it.
+func (p decryptOnly) Exchange() string {
+	if named, ok := p.decrypter.(interface{ Exchange() string }); ok {
+		return named.Exchange()
+	}
+	return ""
+}
+
+// Zero retires the wrapped pricer, if it has a Zero method.
+func (p decryptOnly) Zero() {
+	zero(p.decrypter)
+}
+
+// encryptOnly is a pricer whose Decrypt fails with ErrForbiddenByRole.
+type encryptOnly struct {
+	encrypter pricers.Encrypter
+}
+
+func (p encryptOnly) Encrypt(seed string, price float64) (string, error) {
+	return p.encrypter.Encrypt(seed, price)
+}
+
+func (p encryptOnly) Decrypt(encryptedPrice string) (float64, error) {
+	return 0, ErrForbiddenByRole
+}
+
+// Zero retires the wrapped pricer, if it has a Zero method.
+func (p encryptOnly) Zero() {
+	zero(p.encrypter)
+}
+
+// zero zeroes the keys of a pricer having a Zero method.
+func zero(pricer interface{}) {
+	if zeroer, ok := pricer.(interface{ Zero() }); ok {
+		zeroer.Zero()
+	}
+}
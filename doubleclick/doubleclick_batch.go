@@ -0,0 +1,76 @@
+package doubleclick
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// batchChunkSize is the number of prices a worker claims at once.
+const batchChunkSize = 256
+
+// Result holds the outcome of a single decryption in a batch.
+type Result struct {
+	Price float64
+	Err   error
+}
+
+// DecryptBatch decrypts encrypted prices, fanning work out to workers
+// goroutines (GOMAXPROCS when workers <= 0).
+// Results are returned in input order, per item errors being reported in
+// Result.Err. Once ctx is done, remaining items are reported with ctx error.
+func (dc *DoubleClickPricer) DecryptBatch(ctx context.Context, encryptedPrices []string, workers int) []Result {
+	results := make([]Result, len(encryptedPrices))
+	if len(encryptedPrices) == 0 {
+		return results
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunks := (len(encryptedPrices) + batchChunkSize - 1) / batchChunkSize
+	if workers > chunks {
+		workers = chunks
+	}
+
+	var next int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			// Each worker reuses the same state and buffer for all its items.
+			state := dc.getState()
+			defer dc.states.put(state)
+			buf := make([]byte, 0, dc.encodedLen)
+
+			for {
+				start := int(atomic.AddInt64(&next, batchChunkSize)) - batchChunkSize
+				if start >= len(encryptedPrices) {
+					return
+				}
+				end := start + batchChunkSize
+				if end > len(encryptedPrices) {
+					end = len(encryptedPrices)
+				}
+
+				if err := ctx.Err(); err != nil {
+					for i := start; i < end; i++ {
+						results[i].Err = err
+					}
+					continue
+				}
+
+				for i := start; i < end; i++ {
+					buf = append(buf[:0], encryptedPrices[i]...)
+					results[i].Price, results[i].Err = dc.decrypt(state, buf)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
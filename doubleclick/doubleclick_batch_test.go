@@ -0,0 +1,143 @@
+package doubleclick
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func buildNewBatch(t testing.TB, pricer *DoubleClickPricer, size int) ([]string, []float64) {
+	encryptedPrices := make([]string, size)
+	clearPrices := make([]float64, size)
+	for i := range encryptedPrices {
+		clearPrices[i] = float64(i) / 100
+		encrypted, err := pricer.Encrypt(strconv.Itoa(i), clearPrices[i])
+		if err != nil {
+			t.Fatal("Encryption failed. Error : ", err)
+		}
+		encryptedPrices[i] = encrypted
+	}
+	return encryptedPrices, clearPrices
+}
+
+func TestDecryptBatch(t *testing.T) {
+	// Setup:
+	var pricer *DoubleClickPricer
+	var err error
+	pricer, err = buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	encryptedPrices, clearPrices := buildNewBatch(t, pricer, 1000)
+	encryptedPrices[10] = ""
+	encryptedPrices[500] = "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA"
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		// Execute:
+		results := pricer.DecryptBatch(context.Background(), encryptedPrices, workers)
+
+		// Verify:
+		assert.Len(t, results, len(encryptedPrices))
+		for i, result := range results {
+			switch i {
+			case 10:
+				assert.ErrorIs(t, result.Err, ErrWrongSize)
+			case 500:
+				assert.ErrorIs(t, result.Err, ErrWrongSignature)
+			default:
+				assert.Nil(t, result.Err, "Decryption failed. Error : %s", result.Err)
+				assert.InDelta(t, clearPrices[i], result.Price, 0.001, "Decryption failed. Should be : %f but was : %f", clearPrices[i], result.Price)
+			}
+		}
+	}
+}
+
+func TestDecryptBatchCancelled(t *testing.T) {
+	// Setup:
+	var pricer *DoubleClickPricer
+	var err error
+	pricer, err = buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	encryptedPrices, _ := buildNewBatch(t, pricer, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Execute:
+	results := pricer.DecryptBatch(ctx, encryptedPrices, 4)
+
+	// Verify:
+	for _, result := range results {
+		assert.Equal(t, context.Canceled, result.Err)
+	}
+}
+
+func TestDecryptBatchEmpty(t *testing.T) {
+	// Setup:
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	// Execute:
+	results := pricer.DecryptBatch(context.Background(), nil, 0)
+
+	// Verify:
+	assert.Empty(t, results)
+}
+
+func BenchmarkDecryptLoop(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	encryptedPrices, _ := buildNewBatch(b, pricer, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, encryptedPrice := range encryptedPrices {
+			if _, err := pricer.Decrypt(encryptedPrice); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecryptBatch(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	encryptedPrices, _ := buildNewBatch(b, pricer, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pricer.DecryptBatch(context.Background(), encryptedPrices, 0)
+	}
+}
+
+func BenchmarkDecryptBatchSingleWorker(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	encryptedPrices, _ := buildNewBatch(b, pricer, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pricer.DecryptBatch(context.Background(), encryptedPrices, 1)
+	}
+}
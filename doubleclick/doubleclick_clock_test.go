@@ -0,0 +1,124 @@
+package doubleclick
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// fixedTime is the time IV timestamps are generated at in tests.
+var fixedTime = time.Date(2024, time.January, 2, 3, 4, 5, 678901000, time.UTC)
+
+func fixedClock() time.Time {
+	return fixedTime
+}
+
+func buildNewClockPricer(t *testing.T, options ...Option) *DoubleClickPricer {
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+		options...,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	return pricer
+}
+
+func TestEncryptNowIsReproducible(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t,
+		WithClock(fixedClock),
+		WithRand(bytes.NewReader([]byte{0, 1, 2, 3, 4, 5, 6, 7})))
+
+	// Execute:
+	encrypted, err := pricer.EncryptNow(1.354)
+
+	// Verify:
+	assert.NoError(t, err)
+	assert.Equal(t, "ZZN9JQAKW_UAAQIDBAUGB7SCKZAXEYUKQWAfvw", encrypted)
+	decoded, _ := base64.RawURLEncoding.DecodeString(encrypted)
+	// 1704164645 seconds, 678901 microseconds, then the random bytes.
+	assert.Equal(t, "65937d25000a5bf50001020304050607", hex.EncodeToString(decoded[:16]))
+}
+
+func TestEncryptNowWithSeededRand(t *testing.T) {
+	// Setup:
+	first := buildNewClockPricer(t, WithClock(fixedClock), WithRand(rand.New(rand.NewSource(42))))
+	second := buildNewClockPricer(t, WithClock(fixedClock), WithRand(rand.New(rand.NewSource(42))))
+
+	for i := 0; i < 3; i++ {
+		// Execute:
+		a, errA := first.EncryptNow(1.354)
+		b, errB := second.EncryptNow(1.354)
+
+		// Verify:
+		assert.NoError(t, errA)
+		assert.NoError(t, errB)
+		assert.Equal(t, a, b)
+	}
+}
+
+func TestEncryptNowDefaults(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t)
+	before := time.Now().Truncate(time.Microsecond)
+
+	// Execute:
+	a, errA := pricer.EncryptNow(1.354)
+	b, errB := pricer.EncryptNow(1.354)
+	price, at, err := pricer.DecryptWithTime(a)
+
+	// Verify:
+	assert.NoError(t, errA)
+	assert.NoError(t, errB)
+	assert.NotEqual(t, a, b)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.354, price, 0.000001)
+	assert.False(t, at.Before(before))
+	assert.False(t, at.After(time.Now()))
+}
+
+func TestDecryptWithTime(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t, WithClock(fixedClock))
+	encrypted, err := pricer.EncryptNow(3.24)
+	assert.NoError(t, err)
+
+	// Execute:
+	price, at, err := pricer.DecryptWithTime(encrypted)
+	_, _, wrongErr := pricer.DecryptWithTime("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+
+	// Verify:
+	assert.NoError(t, err)
+	assert.InDelta(t, 3.24, price, 0.000001)
+	assert.True(t, fixedTime.Equal(at), "Time should be %s but was %s", fixedTime, at)
+	assert.ErrorIs(t, wrongErr, ErrWrongSignature)
+}
+
+func TestEncryptNowFailures(t *testing.T) {
+	// Setup:
+	errRand := bytes.ErrTooLarge
+	failing := buildNewClockPricer(t, WithRand(iotest.ErrReader(errRand)))
+	exhausted := buildNewClockPricer(t, WithRand(bytes.NewReader(nil)))
+
+	// Execute:
+	_, randErr := failing.EncryptNow(1)
+	_, rangeErr := exhausted.EncryptNow(-1)
+	_, exhaustedErr := exhausted.EncryptNow(1)
+
+	// Verify:
+	assert.ErrorIs(t, randErr, errRand)
+	assert.ErrorIs(t, rangeErr, ErrOutOfRange)
+	assert.Error(t, exhaustedErr)
+}
@@ -0,0 +1,212 @@
+package doubleclick
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// Codec encodes encrypted prices to text and decodes them back.
+// A *base64.Encoding is a Codec.
+type Codec interface {
+	EncodedLen(n int) int
+	Encode(dst []byte, src []byte)
+	Decode(dst []byte, src []byte) (int, error)
+}
+
+// Codecs encrypted prices can be encoded with.
+var (
+	// Base64URL is unpadded web safe base64, from specs. It is the default.
+	Base64URL Codec = base64.RawURLEncoding
+	// Base64URLPadded is padded web safe base64.
+	Base64URLPadded Codec = base64.URLEncoding
+	// Base64 is padded standard base64, with + and /.
+	Base64 Codec = base64.StdEncoding
+	// Hex is lowercase hexadecimal, uppercase being accepted on input.
+	Hex Codec = hexCodec{}
+)
+
+var codecs = map[string]Codec{
+	"base64url":        Base64URL,
+	"base64url-padded": Base64URLPadded,
+	"base64":           Base64,
+	"hex":              Hex,
+}
+
+// codecDescriptions describe the codecs in error messages.
+var codecDescriptions = map[string]string{
+	"base64url":        "web safe base64",
+	"base64url-padded": "padded web safe base64",
+	"base64":           "standard base64",
+	"hex":              "hexadecimal",
+}
+
+// codecName : Returns the name of a codec, empty for custom codecs.
+// Unpadded standard base64, decoded leniently, is named base64.
+func codecName(codec Codec) string {
+	if codec == Codec(base64.RawStdEncoding) {
+		return "base64"
+	}
+	for name, c := range codecs {
+		if c == codec {
+			return name
+		}
+	}
+	return ""
+}
+
+// ParseCodec : Returns the codec of a name, one of CodecNames.
+func ParseCodec(name string) (Codec, error) {
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	return codec, nil
+}
+
+// CodecNames : Returns the sorted names of the codecs.
+func CodecNames() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type hexCodec struct{}
+
+func (hexCodec) EncodedLen(n int) int {
+	return hex.EncodedLen(n)
+}
+
+func (hexCodec) Encode(dst []byte, src []byte) {
+	hex.Encode(dst, src)
+}
+
+func (hexCodec) Decode(dst []byte, src []byte) (int, error) {
+	return hex.Decode(dst, src)
+}
+
+// maxEscapes is the number of times lenient decoding percent-decodes a token.
+const maxEscapes = 2
+
+// decodeToken decodes an encrypted price to state.message, returning
+// the decoded message, or a *DecryptError of kind KindSize or KindEncoding.
+func (dc *DoubleClickPricer) decodeToken(state *hmacState, encryptedPrice []byte) ([]byte, error) {
+	if dc.lenient {
+		return dc.decodeLenient(state, encryptedPrice)
+	}
+
+	// Just to be safe remove padding if it was added by mistake
+	if dc.codec == Base64URL {
+		encryptedPrice = bytes.TrimRight(encryptedPrice, "=")
+	}
+	if len(encryptedPrice) != dc.encodedLen {
+		return nil, dc.newDecryptError(KindSize, encryptedPrice, dc.sizeError(len(encryptedPrice)))
+	}
+	return dc.decodeWith(state, dc.codec, encryptedPrice)
+}
+
+// decodeWith decodes an encrypted price to state.message with the given codec.
+func (dc *DoubleClickPricer) decodeWith(state *hmacState, codec Codec, encryptedPrice []byte) ([]byte, error) {
+	// The base64 decoder skips new lines, so an input of the right size may
+	// decode to less bytes, leaving stale bytes from a previous call in the buffer.
+	decoded := state.message
+	state.codec = codec
+	n, err := codec.Decode(decoded, encryptedPrice)
+	if err != nil {
+		decryptError := dc.newDecryptError(KindEncoding, encryptedPrice, err)
+		decryptError.Codec = codecName(codec)
+		return nil, decryptError
+	}
+	if n != dc.decodedLen {
+		return nil, dc.newDecryptError(KindSize, encryptedPrice, fmt.Errorf("decodes to %d bytes instead of %d", n, dc.decodedLen))
+	}
+	return decoded, nil
+}
+
+// decodeLenient normalizes an encrypted price before decoding it:
+// it is percent-decoded, up to twice, spaces are turned back into the +
+// they were decoded from in a query string, padding is removed, and it is
+// decoded as hex or base64, web safe or standard, according to its size.
+// Tokens mixing both base64 alphabets are rejected.
+func (dc *DoubleClickPricer) decodeLenient(state *hmacState, encryptedPrice []byte) ([]byte, error) {
+	original := encryptedPrice
+	if len(encryptedPrice) > dc.maxLenientLen() {
+		return nil, dc.newDecryptError(KindSize, original, dc.sizeError(len(encryptedPrice)))
+	}
+
+	token := append(state.token[:0], encryptedPrice...)
+	for i := 0; i < maxEscapes && bytes.IndexByte(token, '%') >= 0; i++ {
+		var err error
+		if token, err = unescape(token); err != nil {
+			return nil, dc.newDecryptError(KindEncoding, original, err)
+		}
+	}
+	state.token = token
+	token = bytes.TrimRight(token, "=")
+
+	switch len(token) {
+	case hex.EncodedLen(dc.decodedLen):
+		return dc.decodeWith(state, Hex, token)
+	case base64.RawURLEncoding.EncodedLen(dc.decodedLen):
+		urlSafe := bytes.ContainsAny(token, "-_")
+		standard := bytes.ContainsAny(token, "+/ ")
+		if urlSafe && standard {
+			return nil, dc.newDecryptError(KindEncoding, original, fmt.Errorf("mixes web safe and standard base64 alphabets"))
+		}
+		if !standard {
+			return dc.decodeWith(state, base64.RawURLEncoding, token)
+		}
+		for i, c := range token {
+			if c == ' ' {
+				token[i] = '+'
+			}
+		}
+		return dc.decodeWith(state, base64.RawStdEncoding, token)
+	}
+	return nil, dc.newDecryptError(KindSize, original, dc.sizeError(len(token)))
+}
+
+// TokenCodec : Returns the name of the codec the pricer decodes an encrypted
+// price with, see CodecNames, which lenient decoding picks per encrypted
+// price. It is empty if the encrypted price can't be decoded, or if the
+// codec set with WithCodec is not one of the named ones.
+func (dc *DoubleClickPricer) TokenCodec(encryptedPrice string) string {
+	state := dc.getState()
+	defer dc.states.put(state)
+	if _, err := dc.decodeToken(state, []byte(encryptedPrice)); err != nil {
+		return ""
+	}
+	return codecName(state.codec)
+}
+
+// maxLenientLen : Returns the size above which lenient decoding rejects
+// encrypted prices without looking at them: hex, percent-encoded twice.
+func (dc *DoubleClickPricer) maxLenientLen() int {
+	return 5 * hex.EncodedLen(dc.decodedLen)
+}
+
+// unescape percent-decodes b in place.
+func unescape(b []byte) ([]byte, error) {
+	n := 0
+	for i := 0; i < len(b); i++ {
+		if b[i] != '%' {
+			b[n] = b[i]
+			n++
+			continue
+		}
+		if i+2 >= len(b) {
+			return nil, fmt.Errorf("truncated percent-encoding")
+		}
+		if _, err := hex.Decode(b[n:n+1], b[i+1:i+3]); err != nil {
+			return nil, fmt.Errorf("invalid percent-encoding: %w", err)
+		}
+		n++
+		i += 2
+	}
+	return b[:n], nil
+}
@@ -0,0 +1,140 @@
+package doubleclick
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecs(t *testing.T) {
+	var testCases = []struct {
+		codec     Codec
+		encrypted string
+	}{
+		{Base64URL, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA"},
+		{Base64URLPadded, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA=="},
+		{Base64, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu+2SA=="},
+		{Hex, "d41d8cd98f00b204e9800998ecf8427e00e8f662466af1cebaefb648"},
+	}
+
+	for _, testCase := range testCases {
+		// Setup:
+		pricer := buildNewClockPricer(t, WithCodec(testCase.codec))
+
+		// Execute:
+		encrypted, encryptErr := pricer.Encrypt("", 1.354)
+		price, decryptErr := pricer.Decrypt(testCase.encrypted)
+
+		// Verify:
+		assert.NoError(t, encryptErr)
+		assert.Equal(t, testCase.encrypted, encrypted)
+		assert.Equal(t, len(testCase.encrypted), pricer.EncodedSize())
+		assert.NoError(t, decryptErr)
+		assert.InDelta(t, 1.354, price, 0.000001)
+	}
+}
+
+func TestStrictDecodingRejectsOtherEncodings(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t)
+	padded := buildNewClockPricer(t, WithCodec(Base64URLPadded))
+
+	// Execute:
+	_, standardErr := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu+2SA")
+	_, hexErr := pricer.Decrypt("d41d8cd98f00b204e9800998ecf8427e00e8f662466af1cebaefb648")
+	_, escapedErr := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%2B2SA")
+	_, unpaddedErr := padded.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.ErrorIs(t, standardErr, ErrWrongEncoding)
+	assert.ErrorIs(t, hexErr, ErrWrongSize)
+	assert.ErrorIs(t, escapedErr, ErrWrongSize)
+	assert.ErrorIs(t, unpaddedErr, ErrWrongSize)
+}
+
+func TestLenientDecoding(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t, WithLenientDecoding())
+	var encryptedPrices = []string{
+		"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA",
+		"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA==",
+		"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu+2SA==",
+		"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu 2SA",
+		"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%2B2SA%3D%3D",
+		"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%252B2SA%253D%253D",
+		"d41d8cd98f00b204e9800998ecf8427e00e8f662466af1cebaefb648",
+		"D41D8CD98F00B204E9800998ECF8427E00E8F662466AF1CEBAEFB648",
+	}
+
+	for _, encryptedPrice := range encryptedPrices {
+		// Execute:
+		price, err := pricer.Decrypt(encryptedPrice)
+		bytesPrice, bytesErr := pricer.DecryptBytes([]byte(encryptedPrice))
+
+		// Verify:
+		assert.NoError(t, err, encryptedPrice)
+		assert.InDelta(t, 1.354, price, 0.000001, encryptedPrice)
+		assert.NoError(t, bytesErr, encryptedPrice)
+		assert.Equal(t, price, bytesPrice, encryptedPrice)
+	}
+}
+
+func TestLenientDecodingFailures(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t, WithLenientDecoding())
+	var testCases = []struct {
+		encryptedPrice string
+		kind           ErrorKind
+	}{
+		// Both base64 alphabets.
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2S/", KindEncoding},
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%2", KindEncoding},
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%zz2SA", KindEncoding},
+		// Escaped 3 times.
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%25252B2SA", KindSize},
+		{strings.Repeat("A", 1000), KindSize},
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-3SA", KindSignature},
+		{"zz1d8cd98f00b204e9800998ecf8427e00e8f662466af1cebaefb648", KindEncoding},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		_, err := pricer.Decrypt(testCase.encryptedPrice)
+
+		// Verify:
+		assert.Equal(t, testCase.kind, KindOf(err), "%s: %v", testCase.encryptedPrice, err)
+	}
+}
+
+func TestTokenCodec(t *testing.T) {
+	// Setup:
+	strict := buildNewClockPricer(t, WithCodec(Base64))
+	lenient := buildNewClockPricer(t, WithLenientDecoding())
+
+	// Execute:
+	standard := strict.TokenCodec("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu+2SA==")
+	urlSafe := strict.TokenCodec("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA==")
+	lenientHex := lenient.TokenCodec("d41d8cd98f00b204e9800998ecf8427e00e8f662466af1cebaefb648")
+	lenientStandard := lenient.TokenCodec("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu%2B2SA%3D%3D")
+	lenientURLSafe := lenient.TokenCodec("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.Equal(t, "base64", standard)
+	assert.Empty(t, urlSafe)
+	assert.Equal(t, "hex", lenientHex)
+	assert.Equal(t, "base64", lenientStandard)
+	assert.Equal(t, "base64url", lenientURLSafe)
+}
+
+func TestParseCodec(t *testing.T) {
+	// Execute:
+	codec, err := ParseCodec("hex")
+	_, unknownErr := ParseCodec("base32")
+
+	// Verify:
+	assert.NoError(t, err)
+	assert.Equal(t, Hex, codec)
+	assert.Error(t, unknownErr)
+	assert.Equal(t, []string{"base64", "base64url", "base64url-padded", "hex"}, CodecNames())
+}
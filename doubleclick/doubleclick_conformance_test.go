@@ -0,0 +1,14 @@
+package doubleclick
+
+import (
+	"testing"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/testvectors"
+)
+
+func TestConformance(t *testing.T) {
+	testvectors.Run(t, Protocol, func(v testvectors.Vector) (pricers.Pricer, error) {
+		return NewDoubleClickPricer(v.EncryptionKey, v.IntegrityKey, v.Base64Keys, v.KeyDecodingMode, v.ScaleFactor, false)
+	})
+}
@@ -0,0 +1,199 @@
+package doubleclick
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// ErrInvalidCurrency is returned when a currency code is not made of 3 uppercase letters.
+var ErrInvalidCurrency = errors.New("Currency is not an ISO-4217 code")
+
+// ErrUnknownCurrency is returned when a rate table has no rate for a currency.
+var ErrUnknownCurrency = errors.New("Currency has no rate")
+
+// microsPerUnit is the number of micros in a currency unit.
+const microsPerUnit = 1000000
+
+// Amount is a price in micros of an ISO-4217 currency.
+type Amount struct {
+	Micros   int64
+	Currency string
+}
+
+// String : Returns the amount in currency units, e.g. "1.354000 USD".
+func (a Amount) String() string {
+	sign := ""
+	micros := a.Micros
+	if micros < 0 {
+		sign = "-"
+		micros = -micros
+	}
+	return fmt.Sprintf("%s%d.%06d %s", sign, micros/microsPerUnit, micros%microsPerUnit, a.Currency)
+}
+
+// Rat : Returns the amount in currency units, exactly.
+func (a Amount) Rat() *big.Rat {
+	return big.NewRat(a.Micros, microsPerUnit)
+}
+
+// DecryptAmount decrypts an encrypted price and pairs it with the currency
+// of the bid it was issued for.
+// The decrypted value is converted from the pricer scale factor to micros,
+// rounding half to even. Errors are the ones of Decrypt, or ErrInvalidCurrency.
+// A price too large to be held in micros is out of range.
+func (dc *DoubleClickPricer) DecryptAmount(encryptedPrice string, currency string) (Amount, error) {
+	if !isCurrency(currency) {
+		return Amount{}, ErrInvalidCurrency
+	}
+
+	state := dc.getState()
+	defer dc.states.put(state)
+
+	if _, err := dc.decryptString(state, encryptedPrice); err != nil {
+		return Amount{}, err
+	}
+	// On success, state.data holds the decrypted scaled price, which fits 63 bits.
+	scaled := new(big.Rat).SetInt64(int64(state.scaledPrice()))
+	if dc.scaleFactor != microsPerUnit {
+		scaled.Mul(scaled, big.NewRat(microsPerUnit, 1))
+		scaled.Quo(scaled, new(big.Rat).SetFloat64(dc.scaleFactor))
+	}
+	micros, err := roundHalfEven(scaled)
+	if err != nil {
+		return Amount{}, dc.newDecryptError(KindRange, []byte(encryptedPrice), nil)
+	}
+	return Amount{Micros: micros, Currency: currency}, nil
+}
+
+// Rates provides exchange rates between currencies.
+type Rates interface {
+	// Rate returns the number of to units a from unit is worth.
+	Rate(from string, to string) (*big.Rat, error)
+}
+
+// RateTable holds exchange rates relative to a base currency.
+// A RateTable must not be modified once built, and is then safe for concurrent use.
+type RateTable struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+// rateTableFile is the content of a rate table file.
+type rateTableFile struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+// NewRateTable returns a RateTable from rates, given as decimal strings of
+// how many currency units a base unit is worth, e.g. {"EUR": "0.9215"}
+// for a USD base. Decimal strings are parsed exactly.
+func NewRateTable(base string, rates map[string]string) (*RateTable, error) {
+	if !isCurrency(base) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, base)
+	}
+	table := &RateTable{base: base, rates: map[string]*big.Rat{base: big.NewRat(1, 1)}}
+	for currency, rate := range rates {
+		if !isCurrency(currency) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+		}
+		r, ok := new(big.Rat).SetString(rate)
+		if !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("rate of %s is not a positive number: %q", currency, rate)
+		}
+		table.rates[currency] = r
+	}
+	return table, nil
+}
+
+// LoadRateTable reads a RateTable from a JSON file such as:
+//
+//	{"base": "USD", "rates": {"EUR": "0.9215", "JPY": "149.52"}}
+func LoadRateTable(path string) (*RateTable, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file rateTableFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("rate table %s: %w", path, err)
+	}
+	return NewRateTable(file.Base, file.Rates)
+}
+
+// Base : Returns the currency rates are relative to.
+func (rt *RateTable) Base() string {
+	return rt.base
+}
+
+// Rate returns the number of to units a from unit is worth, crossing
+// rates through the base currency.
+func (rt *RateTable) Rate(from string, to string) (*big.Rat, error) {
+	fromRate, ok := rt.rates[from]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, from)
+	}
+	toRate, ok := rt.rates[to]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, to)
+	}
+	return new(big.Rat).Quo(toRate, fromRate), nil
+}
+
+// Converter converts amounts between currencies.
+type Converter struct {
+	rates Rates
+}
+
+// NewConverter returns a Converter using the given rates.
+func NewConverter(rates Rates) *Converter {
+	return &Converter{rates: rates}
+}
+
+// Convert : Returns the amount in the to currency, rounded half to even to the micro.
+// The conversion is computed exactly, so that it is deterministic.
+func (c *Converter) Convert(amount Amount, to string) (Amount, error) {
+	if !isCurrency(to) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, to)
+	}
+	if amount.Currency == to {
+		return amount, nil
+	}
+	rate, err := c.rates.Rate(amount.Currency, to)
+	if err != nil {
+		return Amount{}, err
+	}
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Micros), rate)
+	micros, err := roundHalfEven(converted)
+	if err != nil {
+		return Amount{}, fmt.Errorf("converting %s to %s: %w", amount, to, err)
+	}
+	return Amount{Micros: micros, Currency: to}, nil
+}
+
+// roundHalfEven : Returns r rounded to the nearest integer, ties to even,
+// or ErrOutOfRange if it doesn't fit an int64.
+func roundHalfEven(r *big.Rat) (int64, error) {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Compare twice the remainder to the denominator.
+	cmp := new(big.Int).Abs(new(big.Int).Lsh(rem, 1)).Cmp(r.Denom())
+	if cmp > 0 || (cmp == 0 && quo.Bit(0) == 1) {
+		if r.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if !quo.IsInt64() {
+		return 0, ErrOutOfRange
+	}
+	return quo.Int64(), nil
+}
+
+// isCurrency tells whether code looks like an ISO-4217 currency code.
+func isCurrency(code string) bool {
+	return len(code) == 3 && strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
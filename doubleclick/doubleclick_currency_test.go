@@ -0,0 +1,184 @@
+package doubleclick
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func TestDecryptAmount(t *testing.T) {
+	// Setup:
+	micro := buildNewClockPricer(t)
+	scaled, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		2000000,
+		false,
+	)
+	assert.NoError(t, err)
+
+	// Execute:
+	amount, err := micro.DecryptAmount("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", "USD")
+	scaledAmount, scaledErr := scaled.DecryptAmount("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGVwr-Q_z9Cw", "EUR")
+	_, currencyErr := micro.DecryptAmount("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", "usd")
+	_, signatureErr := micro.DecryptAmount("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA", "USD")
+
+	// Verify:
+	assert.NoError(t, err)
+	assert.Equal(t, Amount{Micros: 1354000, Currency: "USD"}, amount)
+	assert.Equal(t, "1.354000 USD", amount.String())
+	assert.NoError(t, scaledErr)
+	assert.Equal(t, Amount{Micros: 1354000, Currency: "EUR"}, scaledAmount)
+	assert.ErrorIs(t, currencyErr, ErrInvalidCurrency)
+	assert.ErrorIs(t, signatureErr, ErrWrongSignature)
+}
+
+func TestConvert(t *testing.T) {
+	// Setup:
+	rates, err := LoadRateTable("testdata/rates.json")
+	assert.NoError(t, err)
+	converter := NewConverter(rates)
+
+	var testCases = []struct {
+		amount   Amount
+		to       string
+		expected Amount
+	}{
+		{Amount{1354000, "USD"}, "EUR", Amount{1247711, "EUR"}},
+		{Amount{1354000, "USD"}, "USD", Amount{1354000, "USD"}},
+		// 1 / 0.9215 = 1.085187...
+		{Amount{1000000, "EUR"}, "USD", Amount{1085187, "USD"}},
+		// 10 * 149.52 / 0.9215 = 1622.571893652...
+		{Amount{10000000, "EUR"}, "JPY", Amount{1622571894, "JPY"}},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		converted, err := converter.Convert(testCase.amount, testCase.to)
+
+		// Verify:
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, converted, "Converting %s to %s", testCase.amount, testCase.to)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	// Setup:
+	rates, err := NewRateTable("USD", map[string]string{"EUR": "0.5"})
+	assert.NoError(t, err)
+	converter := NewConverter(rates)
+
+	// Execute:
+	_, unknownErr := converter.Convert(Amount{1000000, "USD"}, "CHF")
+	_, invalidErr := converter.Convert(Amount{1000000, "USD"}, "eur")
+	_, badRateErr := NewRateTable("USD", map[string]string{"EUR": "-1"})
+	_, badCurrencyErr := NewRateTable("USD", map[string]string{"euro": "1"})
+	_, missingErr := LoadRateTable("testdata/missing.json")
+
+	// Verify:
+	assert.ErrorIs(t, unknownErr, ErrUnknownCurrency)
+	assert.ErrorIs(t, invalidErr, ErrInvalidCurrency)
+	assert.Error(t, badRateErr)
+	assert.ErrorIs(t, badCurrencyErr, ErrInvalidCurrency)
+	assert.Error(t, missingErr)
+}
+
+func TestRoundHalfEven(t *testing.T) {
+	var testCases = []struct {
+		num, denom int64
+		expected   int64
+	}{
+		{5, 2, 2},
+		{7, 2, 4},
+		{-5, 2, -2},
+		{-7, 2, -4},
+		{12, 5, 2},
+		{-13, 5, -3},
+		{1000001, 2, 500000},
+		{1000003, 2, 500002},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		rounded, err := roundHalfEven(big.NewRat(testCase.num, testCase.denom))
+
+		// Verify:
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, rounded, "Rounding %d/%d", testCase.num, testCase.denom)
+	}
+}
+
+func TestRoundHalfEvenOverflow(t *testing.T) {
+	// Setup:
+	overflow := new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), 63))
+
+	// Execute:
+	_, err := roundHalfEven(overflow)
+	_, negErr := roundHalfEven(new(big.Rat).Neg(new(big.Rat).Add(overflow, big.NewRat(1, 1))))
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.ErrorIs(t, negErr, ErrOutOfRange)
+}
+
+func TestDecryptAmountOverflow(t *testing.T) {
+	// Setup:
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1,
+		false,
+	)
+	assert.NoError(t, err)
+	encrypted, err := pricer.Encrypt("", 5e17)
+	assert.NoError(t, err)
+
+	// Execute:
+	amount, err := pricer.DecryptAmount(encrypted, "USD")
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.Equal(t, KindRange, KindOf(err))
+	assert.Equal(t, Amount{}, amount)
+}
+
+func TestConvertOverflow(t *testing.T) {
+	// Setup:
+	rates, err := LoadRateTable("testdata/rates.json")
+	assert.NoError(t, err)
+	converter := NewConverter(rates)
+
+	// Execute:
+	converted, err := converter.Convert(Amount{90e9 * microsPerUnit, "USD"}, "JPY")
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.Equal(t, Amount{}, converted)
+}
+
+func TestInvalidScaleFactors(t *testing.T) {
+	for _, scaleFactor := range []float64{0, -1000000, math.NaN(), math.Inf(1)} {
+		// Execute:
+		pricer, err := buildNewDoubleClickPricer(
+			"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+			"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+			false, // Keys are not base64
+			helpers.Hexa,
+			scaleFactor,
+			false,
+		)
+
+		// Verify:
+		assert.Nil(t, pricer)
+		assert.EqualError(t, err, fmt.Sprintf("doubleclick: scale factor %g should be positive and finite", scaleFactor))
+	}
+}
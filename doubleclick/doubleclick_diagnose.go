@@ -0,0 +1,235 @@
+package doubleclick
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// KeyConfig describes how a pricer's keys are given.
+type KeyConfig struct {
+	EncryptionKey   string
+	IntegrityKey    string
+	IsBase64Keys    bool
+	KeyDecodingMode helpers.KeyDecodingMode
+}
+
+// Candidate is a permutation of a KeyConfig tried by Diagnose.
+type Candidate struct {
+	KeyConfig
+	// Swapped tells whether the encryption and integrity keys are swapped
+	// compared to the diagnosed configuration.
+	Swapped bool
+	// Valid is the number of sample tokens whose signature validates.
+	Valid int
+	// Err is the reason the keys could not be decoded, if any.
+	Err error
+}
+
+// Diagnosis is the result of Diagnose.
+type Diagnosis struct {
+	// Configured is the diagnosed configuration.
+	Configured Candidate
+	// Matches are the other permutations validating at least one sample
+	// token, the ones closest to the diagnosed configuration first.
+	// Permutations decoding to the same keys as a closer one are left out.
+	Matches []Candidate
+	// Tokens is the number of sample tokens.
+	Tokens int
+	// Malformed is the number of sample tokens that are not encrypted
+	// prices whatever the keys, having a wrong size or encoding.
+	Malformed int
+	// Explanation tells in plain words what is wrong, if anything.
+	Explanation string
+}
+
+// OK : Returns true if the diagnosed configuration validates every well-formed token.
+func (d Diagnosis) OK() bool {
+	return d.Configured.Err == nil && d.Configured.Valid > 0 && d.Configured.Valid == d.Tokens-d.Malformed
+}
+
+// Diagnose tries the plausible permutations of key order, key decoding
+// mode and base64 handling of config against sample tokens, reporting
+// which ones validate the signatures, with a human-readable explanation.
+// Tokens should be encrypted prices issued by the exchange for these keys.
+// Options are the ones of the diagnosed pricer, e.g. WithCodec or
+// WithPadSize, which tell whether tokens are well-formed.
+func Diagnose(config KeyConfig, tokens []string, options ...Option) Diagnosis {
+	d := Diagnosis{Tokens: len(tokens)}
+	probe, err := newDoubleClickPricer(helpers.NewKey(nil), helpers.NewKey(nil), 1000000, false, options)
+	if err != nil {
+		d.Configured = Candidate{KeyConfig: config, Err: err}
+		d.Explanation = fmt.Sprintf("The pricer options are invalid: %s.", err)
+		return d
+	}
+	for _, token := range tokens {
+		if !isWellFormed(probe, token) {
+			d.Malformed++
+		}
+	}
+
+	// The first permutation is the diagnosed configuration itself.
+	var seen [][2][]byte
+	for i, candidate := range permutations(config) {
+		var keys [2][]byte
+		keys[0], candidate.Err = helpers.DecodeKey(candidate.EncryptionKey, candidate.IsBase64Keys, candidate.KeyDecodingMode)
+		if candidate.Err == nil {
+			keys[1], candidate.Err = helpers.DecodeKey(candidate.IntegrityKey, candidate.IsBase64Keys, candidate.KeyDecodingMode)
+		}
+		if candidate.Err == nil {
+			if containsKeys(seen, keys) {
+				continue
+			}
+			seen = append(seen, keys)
+			candidate.Valid = validate(candidate.KeyConfig, tokens, options)
+		}
+
+		if i == 0 {
+			d.Configured = candidate
+		} else if candidate.Valid > 0 {
+			d.Matches = append(d.Matches, candidate)
+		}
+	}
+
+	d.Explanation = d.explain(config)
+	return d
+}
+
+// isWellFormed tells whether a token is an encrypted price, whatever the
+// keys, decoding it as the probe pricer does.
+func isWellFormed(probe *DoubleClickPricer, token string) bool {
+	state := probe.getState()
+	defer probe.states.put(state)
+	_, err := probe.decodeToken(state, []byte(token))
+	return err == nil
+}
+
+func containsKeys(seen [][2][]byte, keys [2][]byte) bool {
+	for _, s := range seen {
+		if bytes.Equal(s[0], keys[0]) && bytes.Equal(s[1], keys[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// permutations : Returns every permutation of config, the closest ones first.
+func permutations(config KeyConfig) []Candidate {
+	var candidates []Candidate
+	for _, swapped := range []bool{false, true} {
+		for _, isBase64Keys := range []bool{config.IsBase64Keys, !config.IsBase64Keys} {
+			for _, mode := range []helpers.KeyDecodingMode{config.KeyDecodingMode, otherMode(config.KeyDecodingMode)} {
+				candidate := Candidate{KeyConfig: KeyConfig{
+					EncryptionKey:   config.EncryptionKey,
+					IntegrityKey:    config.IntegrityKey,
+					IsBase64Keys:    isBase64Keys,
+					KeyDecodingMode: mode,
+				}, Swapped: swapped}
+				if swapped {
+					candidate.EncryptionKey, candidate.IntegrityKey = config.IntegrityKey, config.EncryptionKey
+				}
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return differences(config, candidates[i]) < differences(config, candidates[j])
+	})
+	return candidates
+}
+
+// differences : Returns the number of settings a candidate changes from config.
+func differences(config KeyConfig, candidate Candidate) int {
+	n := 0
+	if candidate.Swapped {
+		n++
+	}
+	if candidate.IsBase64Keys != config.IsBase64Keys {
+		n++
+	}
+	if candidate.KeyDecodingMode != config.KeyDecodingMode {
+		n++
+	}
+	return n
+}
+
+func otherMode(mode helpers.KeyDecodingMode) helpers.KeyDecodingMode {
+	if mode == helpers.Hexa {
+		return helpers.Utf8
+	}
+	return helpers.Hexa
+}
+
+// validate : Returns the number of tokens whose signature validates with the given keys.
+func validate(config KeyConfig, tokens []string, options []Option) int {
+	pricer, err := NewDoubleClickPricer(config.EncryptionKey, config.IntegrityKey, config.IsBase64Keys, config.KeyDecodingMode, 1000000, false, options...)
+	if err != nil {
+		return 0
+	}
+	valid := 0
+	for _, token := range tokens {
+		if _, err := pricer.Decrypt(token); err == nil || KindOf(err) == KindRange {
+			valid++
+		}
+	}
+	return valid
+}
+
+// explain : Returns the explanation of the diagnosis.
+func (d Diagnosis) explain(config KeyConfig) string {
+	wellFormed := d.Tokens - d.Malformed
+	var b strings.Builder
+
+	if d.Malformed > 0 {
+		fmt.Fprintf(&b, "%d of %d sample tokens are not encrypted prices (wrong size or encoding), whatever the keys. ", d.Malformed, d.Tokens)
+	}
+	switch {
+	case d.Tokens == 0 || wellFormed == 0:
+		b.WriteString("No well-formed sample token to diagnose the keys with.")
+	case d.Configured.Err != nil:
+		fmt.Fprintf(&b, "The keys cannot be decoded as configured: %s. ", d.Configured.Err)
+	case d.OK():
+		fmt.Fprintf(&b, "The configuration validates the signature of all %d well-formed sample tokens.", wellFormed)
+		return strings.TrimSpace(b.String())
+	case d.Configured.Valid > 0:
+		fmt.Fprintf(&b, "The configuration validates %d of %d well-formed sample tokens: the other ones were likely issued for other keys, e.g. by another exchange or before a key rotation.", d.Configured.Valid, wellFormed)
+		return strings.TrimSpace(b.String())
+	default:
+		fmt.Fprintf(&b, "The configuration validates none of the %d well-formed sample tokens. ", wellFormed)
+	}
+	if wellFormed == 0 {
+		return strings.TrimSpace(b.String())
+	}
+
+	for _, match := range d.Matches {
+		if match.Valid == wellFormed {
+			fmt.Fprintf(&b, "Every signature validates when %s.", strings.Join(changes(config, match), " and "))
+			return b.String()
+		}
+	}
+	if len(d.Matches) > 0 {
+		match := d.Matches[0]
+		fmt.Fprintf(&b, "%d of %d signatures validate when %s, sample tokens may come from several exchanges.", match.Valid, wellFormed, strings.Join(changes(config, match), " and "))
+		return b.String()
+	}
+	b.WriteString("No permutation of key order, key decoding mode or base64 handling validates any signature: the keys are likely not the ones the exchange uses, e.g. keys of another exchange or account.")
+	return b.String()
+}
+
+// changes : Returns the changes a candidate makes to config, in plain words.
+func changes(config KeyConfig, candidate Candidate) []string {
+	var changes []string
+	if candidate.Swapped {
+		changes = append(changes, "the encryption and integrity keys are swapped")
+	}
+	if candidate.KeyDecodingMode != config.KeyDecodingMode {
+		changes = append(changes, fmt.Sprintf("the key decoding mode is %q instead of %q", candidate.KeyDecodingMode, config.KeyDecodingMode))
+	}
+	if candidate.IsBase64Keys != config.IsBase64Keys {
+		changes = append(changes, fmt.Sprintf("base64 keys is %t instead of %t", candidate.IsBase64Keys, config.IsBase64Keys))
+	}
+	return changes
+}
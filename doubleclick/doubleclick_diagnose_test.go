@@ -0,0 +1,157 @@
+package doubleclick
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+const (
+	googleEncryptionKey    = "ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU"
+	googleIntegrityKey     = "vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U"
+	googleEncryptionKeyHex = "652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135"
+	googleIntegrityKeyHex  = "bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5"
+)
+
+func TestDiagnoseValidConfiguration(t *testing.T) {
+	// Execute:
+	d := Diagnose(KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8}, googleEncryptedPrices)
+
+	// Verify:
+	assert.True(t, d.OK())
+	assert.Equal(t, 6, d.Configured.Valid)
+	assert.Empty(t, d.Matches)
+	assert.Equal(t, "The configuration validates the signature of all 6 well-formed sample tokens.", d.Explanation)
+}
+
+func TestDiagnoseMisconfigurations(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		config      KeyConfig
+		expected    KeyConfig
+		swapped     bool
+		explanation string
+	}{
+		{
+			name:        "swapped keys",
+			config:      KeyConfig{googleIntegrityKey, googleEncryptionKey, true, helpers.Utf8},
+			expected:    KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8},
+			swapped:     true,
+			explanation: "The configuration validates none of the 6 well-formed sample tokens. Every signature validates when the encryption and integrity keys are swapped.",
+		},
+		{
+			name:        "keys not flagged base64",
+			config:      KeyConfig{googleEncryptionKey, googleIntegrityKey, false, helpers.Utf8},
+			expected:    KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8},
+			explanation: "The configuration validates none of the 6 well-formed sample tokens. Every signature validates when base64 keys is true instead of false.",
+		},
+		{
+			name:        "wrong key decoding mode",
+			config:      KeyConfig{googleEncryptionKeyHex, googleIntegrityKeyHex, false, helpers.Utf8},
+			expected:    KeyConfig{googleEncryptionKeyHex, googleIntegrityKeyHex, false, helpers.Hexa},
+			explanation: "The configuration validates none of the 6 well-formed sample tokens. Every signature validates when the key decoding mode is \"hexa\" instead of \"utf-8\".",
+		},
+		{
+			name:     "swapped keys and wrong key decoding mode",
+			config:   KeyConfig{googleIntegrityKeyHex, googleEncryptionKeyHex, false, helpers.Utf8},
+			expected: KeyConfig{googleEncryptionKeyHex, googleIntegrityKeyHex, false, helpers.Hexa},
+			swapped:  true,
+			explanation: "The configuration validates none of the 6 well-formed sample tokens. Every signature validates when the encryption and integrity keys are swapped" +
+				" and the key decoding mode is \"hexa\" instead of \"utf-8\".",
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		d := Diagnose(testCase.config, googleEncryptedPrices)
+
+		// Verify:
+		assert.False(t, d.OK(), testCase.name)
+		assert.Equal(t, 0, d.Configured.Valid, testCase.name)
+		if assert.NotEmpty(t, d.Matches, testCase.name) {
+			assert.Equal(t, testCase.expected, d.Matches[0].KeyConfig, testCase.name)
+			assert.Equal(t, testCase.swapped, d.Matches[0].Swapped, testCase.name)
+			assert.Equal(t, 6, d.Matches[0].Valid, testCase.name)
+		}
+		assert.Equal(t, testCase.explanation, d.Explanation, testCase.name)
+	}
+}
+
+func TestDiagnoseUndecodableKeys(t *testing.T) {
+	// Execute:
+	d := Diagnose(KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Hexa}, googleEncryptedPrices)
+
+	// Verify:
+	assert.False(t, d.OK())
+	assert.Error(t, d.Configured.Err)
+	assert.Contains(t, d.Explanation, "The keys cannot be decoded as configured")
+	assert.Contains(t, d.Explanation, "Every signature validates when the key decoding mode is \"utf-8\" instead of \"hexa\".")
+}
+
+func TestDiagnoseUnrelatedKeys(t *testing.T) {
+	// Execute:
+	d := Diagnose(KeyConfig{
+		"6356770B3C111C07F778AFD69F16643E9110090FD4C479D91181EED2523788F1",
+		"3588BF6D387E8AEAD4EEC66798255369AF47BFD48B056E8934CEFEF3609C469E",
+		false,
+		helpers.Utf8,
+	}, googleEncryptedPrices)
+
+	// Verify:
+	assert.False(t, d.OK())
+	assert.Empty(t, d.Matches)
+	assert.Contains(t, d.Explanation, "the keys are likely not the ones the exchange uses")
+}
+
+func TestDiagnoseSampleTokens(t *testing.T) {
+	// Setup:
+	tokens := append([]string{"", "not a token", "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7l\nXpg"}, googleEncryptedPrices[:2]...)
+
+	// Execute:
+	d := Diagnose(KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8}, tokens)
+	partial := Diagnose(KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8}, []string{googleEncryptedPrices[0], "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA"})
+	empty := Diagnose(KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8}, nil)
+
+	// Verify:
+	assert.True(t, d.OK())
+	assert.Equal(t, 3, d.Malformed)
+	assert.Equal(t, "3 of 5 sample tokens are not encrypted prices (wrong size or encoding), whatever the keys. "+
+		"The configuration validates the signature of all 2 well-formed sample tokens.", d.Explanation)
+	assert.False(t, partial.OK())
+	assert.Contains(t, partial.Explanation, "The configuration validates 1 of 2 well-formed sample tokens")
+	assert.False(t, empty.OK())
+	assert.Equal(t, "No well-formed sample token to diagnose the keys with.", empty.Explanation)
+}
+
+func TestDiagnoseWithPricerOptions(t *testing.T) {
+	// Setup:
+	config := KeyConfig{googleEncryptionKey, googleIntegrityKey, true, helpers.Utf8}
+	hexTokens := make([]string, len(googleEncryptedPrices))
+	for i, token := range googleEncryptedPrices {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		assert.Nil(t, err)
+		hexTokens[i] = hex.EncodeToString(decoded)
+	}
+
+	// Execute:
+	d := Diagnose(config, hexTokens, WithCodec(Hex))
+	lenient := Diagnose(config, append([]string{hexTokens[0]}, googleEncryptedPrices[1:]...), WithLenientDecoding())
+	defaults := Diagnose(config, hexTokens)
+	invalid := Diagnose(config, googleEncryptedPrices, WithPadSize(4))
+
+	// Verify:
+	assert.True(t, d.OK())
+	assert.Equal(t, 0, d.Malformed)
+	assert.Equal(t, 6, d.Configured.Valid)
+	assert.True(t, lenient.OK())
+	assert.Equal(t, 6, lenient.Configured.Valid)
+	assert.False(t, defaults.OK())
+	assert.Equal(t, 6, defaults.Malformed)
+	assert.False(t, invalid.OK())
+	assert.Error(t, invalid.Configured.Err)
+	assert.Contains(t, invalid.Explanation, "The pricer options are invalid: doubleclick: pad size 4")
+}
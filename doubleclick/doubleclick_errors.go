@@ -0,0 +1,136 @@
+package doubleclick
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorKind : Describing why an encrypted price could not be decrypted.
+type ErrorKind int
+
+const (
+	// KindSize : Encrypted price has a wrong size.
+	KindSize ErrorKind = iota + 1
+	// KindEncoding : Encrypted price is not encoded with the pricer codec.
+	KindEncoding
+	// KindSignature : Encrypted price signature doesn't match.
+	KindSignature
+	// KindKeyMismatch : Encrypted price signature matches the foreign keys
+	// set with WithForeignKeys, rather than the pricer keys.
+	KindKeyMismatch
+	// KindRange : Price is out of the range a price can be encrypted to.
+	KindRange
+)
+
+// String : Returns the ErrorKind string representation.
+func (k ErrorKind) String() string {
+	switch k {
+	case KindSize:
+		return "size"
+	case KindEncoding:
+		return "encoding"
+	case KindSignature:
+		return "signature"
+	case KindKeyMismatch:
+		return "key_mismatch"
+	case KindRange:
+		return "range"
+	}
+	return "unknown"
+}
+
+// Sentinel errors, one per ErrorKind. A DecryptError matches the sentinel
+// of its kind with errors.Is, key mismatches matching ErrWrongSignature too.
+var (
+	ErrWrongSize      = errors.New("Encrypted price has a wrong size")
+	ErrWrongEncoding  = errors.New("Encrypted price has a wrong encoding")
+	ErrWrongSignature = errors.New("Encrypted price signature doesn't match")
+	ErrKeyMismatch    = errors.New("Encrypted price was issued for other keys")
+	ErrOutOfRange     = errors.New("Price is out of range")
+)
+
+// tokenPrefixSize is the number of encrypted price chars kept in errors.
+const tokenPrefixSize = 6
+
+// DecryptError is returned by Decrypt when an encrypted price can't be decrypted.
+type DecryptError struct {
+	Kind ErrorKind
+	// Exchange is the pricer exchange name, set with WithExchange.
+	Exchange string
+	// TokenPrefix holds the first chars of the encrypted price, the rest being redacted.
+	TokenPrefix string
+	// Codec is the name of the codec the encrypted price failed to decode
+	// with, for KindEncoding errors, empty if unknown, see CodecNames.
+	Codec string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// newDecryptError returns a DecryptError, redacting the encrypted price.
+func (dc *DoubleClickPricer) newDecryptError(kind ErrorKind, encryptedPrice []byte, err error) *DecryptError {
+	prefix := encryptedPrice
+	if len(prefix) > tokenPrefixSize {
+		prefix = prefix[:tokenPrefixSize]
+	}
+	return &DecryptError{Kind: kind, Exchange: dc.exchange, TokenPrefix: string(prefix), Err: err}
+}
+
+// Error : Returns the DecryptError message.
+func (e *DecryptError) Error() string {
+	var b strings.Builder
+	b.WriteString("doubleclick: ")
+	if e.Exchange != "" {
+		b.WriteString(e.Exchange)
+		b.WriteString(": ")
+	}
+	b.WriteString("decrypting \"")
+	b.WriteString(e.TokenPrefix)
+	b.WriteString("...\": ")
+	if description, ok := codecDescriptions[e.Codec]; ok && e.Kind == KindEncoding {
+		b.WriteString("Encrypted price is not ")
+		b.WriteString(description)
+	} else {
+		b.WriteString(e.sentinel().Error())
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap : Returns the underlying error.
+func (e *DecryptError) Unwrap() error {
+	return e.Err
+}
+
+// Is : Tells whether target is the sentinel error of the DecryptError kind.
+// A key mismatch being a signature mismatch, it is ErrWrongSignature too.
+func (e *DecryptError) Is(target error) bool {
+	return target == e.sentinel() || (e.Kind == KindKeyMismatch && target == ErrWrongSignature)
+}
+
+func (e *DecryptError) sentinel() error {
+	switch e.Kind {
+	case KindSize:
+		return ErrWrongSize
+	case KindEncoding:
+		return ErrWrongEncoding
+	case KindSignature:
+		return ErrWrongSignature
+	case KindKeyMismatch:
+		return ErrKeyMismatch
+	case KindRange:
+		return ErrOutOfRange
+	}
+	return errors.New("unknown decryption error")
+}
+
+// KindOf returns the kind of a DecryptError wrapped in err, 0 if there is none.
+func KindOf(err error) ErrorKind {
+	var decryptError *DecryptError
+	if errors.As(err, &decryptError) {
+		return decryptError.Kind
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+package doubleclick
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/metrics"
+)
+
+// encryptMicros encrypts raw micros, bypassing Encrypt range checks.
+func encryptMicros(pricer *DoubleClickPricer, micros uint64) string {
+	var data [8]byte
+	var message [decodedSize]byte
+	binary.BigEndian.PutUint64(data[:], micros)
+
+	state := pricer.getState()
+	iv := message[0:16]
+	pad := helpers.HmacSum(state.encryptionKey, iv, nil)
+	for i := range data {
+		message[16+i] = pad[i] ^ data[i]
+	}
+	copy(message[24:28], helpers.HmacSum(state.integrityKey, data[:], iv))
+
+	return base64.RawURLEncoding.EncodeToString(message[:])
+}
+
+func TestDecryptErrorKinds(t *testing.T) {
+	// Setup:
+	var pricer *DoubleClickPricer
+	var err error
+	pricer, err = buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+		WithExchange("adx"),
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	var testCases = []struct {
+		encrypted string
+		kind      ErrorKind
+		sentinel  error
+		message   string
+	}{
+		{"1B2M2Y8Asg", KindSize, ErrWrongSize, `doubleclick: adx: decrypting "1B2M2Y...": Encrypted price has a wrong size: 10 chars instead of 38`},
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2S!", KindEncoding, ErrWrongEncoding, `doubleclick: adx: decrypting "1B2M2Y...": Encrypted price is not web safe base64: illegal base64 data at input byte 37`},
+		{"1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-3SA", KindSignature, ErrWrongSignature, `doubleclick: adx: decrypting "1B2M2Y...": Encrypted price signature doesn't match`},
+		{encryptMicros(pricer, math.MaxUint64), KindRange, ErrOutOfRange, `doubleclick: adx: decrypting "AAAAAA...": Price is out of range`},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		_, err := pricer.Decrypt(testCase.encrypted)
+
+		// Verify:
+		var decryptError *DecryptError
+		assert.True(t, errors.As(err, &decryptError), testCase.encrypted)
+		assert.Equal(t, testCase.kind, decryptError.Kind)
+		assert.Equal(t, testCase.kind, KindOf(err))
+		assert.Equal(t, "adx", decryptError.Exchange)
+		assert.ErrorIs(t, err, testCase.sentinel)
+		assert.Equal(t, testCase.message, err.Error())
+		assert.NotContains(t, err.Error(), testCase.encrypted[tokenPrefixSize:])
+	}
+}
+
+func TestDecryptErrorKeyMismatch(t *testing.T) {
+	// Setup:
+	foreignEncryptionKey, err := helpers.ParseKey("6356770B3C111C07F778AFD69F16643E9110090FD4C479D91181EED2523788F1", false, helpers.Utf8)
+	assert.NoError(t, err)
+	foreignIntegrityKey, err := helpers.ParseKey("3588BF6D387E8AEAD4EEC66798255369AF47BFD48B056E8934CEFEF3609C469E", false, helpers.Utf8)
+	assert.NoError(t, err)
+	pricer := buildNewClockPricer(t, WithExchange("adx"), WithForeignKeys(foreignEncryptionKey, foreignIntegrityKey))
+
+	// Execute:
+	_, mismatchErr := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfn2qP3v2GMq9FzETRA")
+	_, tamperedErr := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-3SA")
+	price, err := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.Equal(t, KindKeyMismatch, KindOf(mismatchErr))
+	assert.ErrorIs(t, mismatchErr, ErrKeyMismatch)
+	assert.ErrorIs(t, mismatchErr, ErrWrongSignature)
+	assert.Equal(t, `doubleclick: adx: decrypting "1B2M2Y...": Encrypted price was issued for other keys`, mismatchErr.Error())
+	assert.Equal(t, metrics.KeyMismatch, OutcomeOf(mismatchErr))
+	assert.Equal(t, KindSignature, KindOf(tamperedErr))
+	assert.False(t, errors.Is(tamperedErr, ErrKeyMismatch))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.354, price)
+}
+
+func TestDecryptErrorNamesCodec(t *testing.T) {
+	var testCases = []struct {
+		codec     Codec
+		lenient   bool
+		encrypted string
+		message   string
+	}{
+		{Hex, false, "z" + strings.Repeat("0", 55), `Encrypted price is not hexadecimal`},
+		{Base64, false, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA==", `Encrypted price is not standard base64`},
+		{Base64URL, true, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu+2S!", `Encrypted price is not standard base64`},
+		{Base64URL, true, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2S+", `Encrypted price has a wrong encoding: mixes web safe and standard base64 alphabets`},
+	}
+
+	for _, testCase := range testCases {
+		// Setup:
+		options := []Option{WithCodec(testCase.codec)}
+		if testCase.lenient {
+			options = append(options, WithLenientDecoding())
+		}
+		pricer, err := buildNewDoubleClickPricer(
+			"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+			"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+			false, // Keys are not base64
+			helpers.Hexa,
+			1000000,
+			false,
+			options...,
+		)
+		assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+		// Execute:
+		_, err = pricer.Decrypt(testCase.encrypted)
+
+		// Verify:
+		assert.Equal(t, KindEncoding, KindOf(err), testCase.encrypted)
+		assert.ErrorIs(t, err, ErrWrongEncoding)
+		assert.Contains(t, err.Error(), testCase.message)
+	}
+}
+
+func TestDecryptErrorUnwrapsBase64Error(t *testing.T) {
+	// Setup:
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	// Execute:
+	_, err = pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu+2SA")
+
+	// Verify:
+	var corruptInputError base64.CorruptInputError
+	assert.True(t, errors.As(err, &corruptInputError))
+	assert.Equal(t, base64.CorruptInputError(34), corruptInputError)
+	assert.False(t, errors.Is(err, ErrWrongSignature))
+}
+
+func TestEncryptOutOfRange(t *testing.T) {
+	// Setup:
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	for _, price := range []float64{-1, math.NaN(), math.Inf(1), math.MaxInt64} {
+		// Execute:
+		_, err := pricer.Encrypt("", price)
+
+		// Verify:
+		assert.ErrorIs(t, err, ErrOutOfRange, "price: %f", price)
+	}
+}
+
+func TestPriceLimits(t *testing.T) {
+	// Setup:
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+		WithPriceLimits(0.5, 10),
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	// Execute:
+	_, errEncrypt := pricer.Encrypt("", 11)
+	_, errDecryptAbove := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJDi7nevR9kUw")
+	_, errDecryptBelow := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGfn_O2Zdh_g")
+	result, errDecrypt := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.ErrorIs(t, errEncrypt, ErrOutOfRange)
+	assert.Equal(t, KindRange, KindOf(errDecryptAbove))
+	assert.Equal(t, KindRange, KindOf(errDecryptBelow))
+	assert.Nil(t, errDecrypt, "Decryption failed. Error : %s", errDecrypt)
+	assert.InDelta(t, 1.354, result, 0.001)
+}
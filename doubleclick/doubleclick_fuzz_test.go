@@ -0,0 +1,121 @@
+package doubleclick
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// googleEncryptedPrices are the encrypted prices of the Google spec examples.
+var googleEncryptedPrices = []string{
+	"anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg",
+	"ce131TRp7waIZI2qOiRr2DMm2sSIeGh_wIAwVQ",
+	"K6tfPnPvN_5E2xS3GssrFYeouJJRkBQqxR_FxQ",
+	"lEzCWnwgB21Dy2_H43PKZeZaNDstZZElZRFTDQ",
+	"L91lB6giyIXh2o4CeUf0F7sCXozKWRXAUeMUfg",
+	"8WY0BgWbds1eEVNFkrXVIr1GU08iueKrP0wXfw",
+}
+
+func buildNewFuzzPricer(f *testing.F) *DoubleClickPricer {
+	pricer, err := buildNewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+	)
+	if err != nil {
+		f.Fatal("Error creating new Pricer : ", err)
+	}
+	return pricer
+}
+
+func FuzzDecrypt(f *testing.F) {
+	pricer := buildNewFuzzPricer(f)
+	for _, encryptedPrice := range googleEncryptedPrices {
+		f.Add(encryptedPrice)
+	}
+	f.Add("")
+	f.Add("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGVwr-Q_z9Cw==")
+	f.Add("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lX==")
+	f.Add("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lX\r\n")
+
+	f.Fuzz(func(t *testing.T, encryptedPrice string) {
+		price, err := pricer.Decrypt(encryptedPrice)
+		if err != nil {
+			var decryptError *DecryptError
+			if !errors.As(err, &decryptError) {
+				t.Fatalf("Decrypt(%q) error is not a *DecryptError: %v", encryptedPrice, err)
+			}
+			return
+		}
+		if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+			t.Fatalf("Decrypt(%q) = %f, which is not a price", encryptedPrice, price)
+		}
+
+		bytesPrice, bytesErr := pricer.DecryptBytes([]byte(encryptedPrice))
+		if bytesErr != nil || bytesPrice != price {
+			t.Fatalf("DecryptBytes(%q) = %f, %v but Decrypt = %f", encryptedPrice, bytesPrice, bytesErr, price)
+		}
+	})
+}
+
+func FuzzEncryptDecrypt(f *testing.F) {
+	pricer := buildNewFuzzPricer(f)
+	f.Add("", 1.354)
+	f.Add("seed", 0.0)
+	f.Add("azertyuiopmlkjhgfdsqwxcvbn", 100.0)
+	f.Add("\x00\xff", 1e12)
+
+	f.Fuzz(func(t *testing.T, seed string, price float64) {
+		encrypted, err := pricer.Encrypt(seed, price)
+		if err != nil {
+			if !errors.Is(err, ErrOutOfRange) {
+				t.Fatalf("Encrypt(%q, %f) failed: %v", seed, price, err)
+			}
+			return
+		}
+
+		decrypted, err := pricer.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt(Encrypt(%q, %f)) failed: %v", seed, price, err)
+		}
+		// Scaled prices are truncated, e.g. 4.1354 * 1e6 being 4135399.999...,
+		// hence a tolerance of one micro, plus the float64 rounding error.
+		if math.Abs(decrypted-price) > 1e-6+price*1e-15 {
+			t.Fatalf("Decrypt(Encrypt(%q, %f)) = %f", seed, price, decrypted)
+		}
+	})
+}
+
+func TestDecryptRejectsNewLines(t *testing.T) {
+	// The base64 decoder skips new lines, a 38 chars input then decoding
+	// to less than 28 bytes. Decrypt used to read past the decoded bytes.
+	pricer, err := buildNewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+	)
+	if err != nil {
+		t.Fatal("Error creating new Pricer : ", err)
+	}
+
+	for _, encryptedPrice := range []string{
+		"anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lX\r\n",
+		"anCGGFJApcfB6ZGc6\nmindhpTrYXHY4ONo7lX\n",
+	} {
+		// Execute:
+		_, err = pricer.Decrypt(encryptedPrice)
+
+		// Verify:
+		if KindOf(err) != KindSize {
+			t.Errorf("Decrypt(%q) error should be of kind %s, got %v", encryptedPrice, KindSize, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package doubleclick
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func TestEncryptDecryptWithSHA256(t *testing.T) {
+	var testCases = []struct {
+		padSize       int
+		signatureSize int
+		encrypted     string
+	}{
+		{DefaultPadSize, 16, "1B2M2Y8AsgTpgAmY7PhCflvF_TzSmbebjUni8WhuD9z4e_9qBUvrxg"},
+		{12, DefaultSignatureSize, "1B2M2Y8AsgTpgAmY7PhCflvF_TzSjR6LNRFNnyzg2TE"},
+	}
+
+	for _, testCase := range testCases {
+		// Setup:
+		pricer := buildNewClockPricer(t, WithHash(sha256.New), WithPadSize(testCase.padSize), WithSignatureSize(testCase.signatureSize))
+
+		// Execute:
+		encrypted, encryptErr := pricer.Encrypt("", 1.354)
+		price, decryptErr := pricer.Decrypt(testCase.encrypted)
+		_, googleErr := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+		// Verify:
+		assert.NoError(t, encryptErr)
+		assert.Equal(t, testCase.encrypted, encrypted)
+		assert.Equal(t, len(testCase.encrypted), pricer.EncodedSize())
+		assert.NoError(t, decryptErr)
+		assert.InDelta(t, 1.354, price, 0.000001)
+		assert.ErrorIs(t, googleErr, ErrWrongSize)
+	}
+}
+
+func TestDefaultHashAndSizes(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t)
+	explicit := buildNewClockPricer(t, WithHash(sha1.New), WithPadSize(8), WithSignatureSize(4))
+
+	// Execute:
+	encrypted, err := pricer.Encrypt("", 1.354)
+	explicitEncrypted, explicitErr := explicit.Encrypt("", 1.354)
+
+	// Verify:
+	assert.NoError(t, err)
+	assert.NoError(t, explicitErr)
+	assert.Equal(t, 38, pricer.EncodedSize())
+	assert.Equal(t, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", encrypted)
+	assert.Equal(t, encrypted, explicitEncrypted)
+}
+
+func TestDecryptRejectsOverflowingPad(t *testing.T) {
+	// Setup:
+	pricer := buildNewClockPricer(t, WithPadSize(12))
+	encrypted, err := pricer.Encrypt("", 1)
+	assert.NoError(t, err)
+
+	// Execute:
+	// Set a leading byte of the scaled price, then sign again.
+	state := pricer.getState()
+	defer pricer.states.put(state)
+	_, err = pricer.decryptString(state, encrypted)
+	assert.NoError(t, err)
+	state.data[0] = 1
+	copy(state.iv[:], state.message[:ivSize])
+	tampered := string(pricer.appendEncrypt(nil, state))
+	_, tamperedErr := pricer.Decrypt(tampered)
+
+	// Verify:
+	assert.Equal(t, KindRange, KindOf(tamperedErr))
+}
+
+func TestInvalidSizes(t *testing.T) {
+	var testCases = []Option{
+		WithPadSize(7),
+		WithPadSize(sha1.Size + 1),
+		WithSignatureSize(3),
+		WithSignatureSize(sha1.Size + 1),
+	}
+
+	for _, option := range testCases {
+		// Execute:
+		_, err := buildNewDoubleClickPricer(
+			"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+			"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+			false, // Keys are not base64
+			helpers.Hexa,
+			1000000,
+			false,
+			option,
+		)
+
+		// Verify:
+		assert.Error(t, err)
+	}
+}
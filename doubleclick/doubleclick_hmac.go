@@ -0,0 +1,135 @@
+package doubleclick
+
+import (
+	"hash"
+	"sync"
+)
+
+// keyedHMAC is an HMAC, as crypto/hmac computes it, whose padded keys can
+// be overwritten, crypto/hmac keeping them out of reach, see wipe.
+type keyedHMAC struct {
+	inner hash.Hash
+	outer hash.Hash
+	ipad  []byte
+	opad  []byte
+	// sum holds the inner sum, so that Sum doesn't allocate.
+	sum []byte
+}
+
+// newKeyedHMAC returns an HMAC of key, hashed first if longer than a block.
+func newKeyedHMAC(newHash func() hash.Hash, key []byte) *keyedHMAC {
+	h := &keyedHMAC{inner: newHash(), outer: newHash()}
+	blockSize := h.inner.BlockSize()
+	h.ipad = make([]byte, blockSize)
+	h.opad = make([]byte, blockSize)
+	h.sum = make([]byte, 0, h.inner.Size())
+	if len(key) > blockSize {
+		h.outer.Write(key)
+		key = h.outer.Sum(h.sum[:0])
+		h.outer.Reset()
+	}
+	copy(h.ipad, key)
+	copy(h.opad, key)
+	clear(h.sum[:cap(h.sum)])
+	for i := range h.ipad {
+		h.ipad[i] ^= 0x36
+		h.opad[i] ^= 0x5c
+	}
+	h.Reset()
+	return h
+}
+
+func (h *keyedHMAC) Write(p []byte) (int, error) {
+	return h.inner.Write(p)
+}
+
+func (h *keyedHMAC) Sum(b []byte) []byte {
+	h.sum = h.inner.Sum(h.sum[:0])
+	h.outer.Reset()
+	h.outer.Write(h.opad)
+	h.outer.Write(h.sum)
+	return h.outer.Sum(b)
+}
+
+func (h *keyedHMAC) Reset() {
+	h.inner.Reset()
+	h.inner.Write(h.ipad)
+}
+
+func (h *keyedHMAC) Size() int {
+	return h.inner.Size()
+}
+
+func (h *keyedHMAC) BlockSize() int {
+	return h.inner.BlockSize()
+}
+
+// wipe overwrites the padded keys and the sums derived from them.
+// The HMAC must not be used afterwards.
+func (h *keyedHMAC) wipe() {
+	clear(h.ipad)
+	clear(h.opad)
+	clear(h.sum[:cap(h.sum)])
+	h.inner.Reset()
+	h.outer.Reset()
+}
+
+// wipe overwrites the HMAC keys and the buffers that held prices or pads.
+func (state *hmacState) wipe() {
+	state.encryptionKey.wipe()
+	state.integrityKey.wipe()
+	clear(state.pad[:cap(state.pad)])
+	clear(state.signature[:cap(state.signature)])
+	clear(state.iv[:])
+	clear(state.data)
+	clear(state.message)
+	clear(state.token[:cap(state.token)])
+}
+
+// statePool holds the HMAC states of a pricer. Unlike a sync.Pool, it
+// keeps every state it was given back within reach, so that zero can
+// overwrite them. It is as fast as a sync.Pool, see BenchmarkStatePool.
+type statePool struct {
+	mu     sync.Mutex
+	free   []*hmacState
+	zeroed bool
+}
+
+// get : Returns a free state, or nil if there is none.
+func (p *statePool) get() *hmacState {
+	p.mu.Lock()
+	n := len(p.free)
+	if n == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	state := p.free[n-1]
+	p.free[n-1] = nil
+	p.free = p.free[:n-1]
+	p.mu.Unlock()
+	return state
+}
+
+// put gives a state back, overwriting it instead once the pool is zeroed,
+// so that states in use when zero is called are overwritten when released.
+func (p *statePool) put(state *hmacState) {
+	p.mu.Lock()
+	if p.zeroed {
+		p.mu.Unlock()
+		state.wipe()
+		return
+	}
+	p.free = append(p.free, state)
+	p.mu.Unlock()
+}
+
+// zero overwrites the free states, and the ones given back afterwards.
+func (p *statePool) zero() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.zeroed = true
+	for _, state := range p.free {
+		state.wipe()
+	}
+	p.free = nil
+}
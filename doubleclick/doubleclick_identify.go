@@ -0,0 +1,154 @@
+package doubleclick
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Layout is a way a token may hold an encrypted price, see Analyze.
+type Layout struct {
+	// Codec is the name of the codec the token is encoded with, see CodecNames.
+	Codec string
+	// Escaped tells whether the token is percent-encoded.
+	Escaped bool
+	// DecodedSize is the size of the decoded token, 28 bytes from specs.
+	DecodedSize int
+	// IVTime is the time held by the initialization vector, if it looks like a timestamp.
+	IVTime time.Time
+	// Score ranks layouts, from 0 to 0.9, 1 being left to verified signatures.
+	Score float64
+	// Reasons tell in plain words what the score is based on.
+	Reasons []string
+}
+
+const (
+	// maxDecodedSize is the size of the largest encrypted price pricers
+	// may be configured for, with SHA-512 pads and signatures.
+	maxDecodedSize = ivSize + 64 + 64
+	// minDecodedSize is the size of the smallest encrypted price pricers
+	// may be configured for.
+	minDecodedSize = ivSize + priceSize + minSignatureSize
+)
+
+// minIVTime is the earliest plausible IV timestamp, specs examples dating from 2000.
+var minIVTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Analyze tells how a token may hold an encrypted price, looking at its
+// length, alphabet and structure, without keys. Layouts are ranked, the
+// most likely first, and none is returned if the token can't be an
+// encrypted price. Unlike decryption, it doesn't tell whether the token
+// was issued for given keys.
+func Analyze(token string) []Layout {
+	return AnalyzeWithClock(token, time.Now)
+}
+
+// AnalyzeWithClock analyzes a token as Analyze does, IV timestamps being
+// plausible up to a day after the time read from clock.
+func AnalyzeWithClock(token string, clock Clock) []Layout {
+	now := clock()
+	b := []byte(token)
+	escaped := bytes.IndexByte(b, '%') >= 0
+	for i := 0; i < maxEscapes && bytes.IndexByte(b, '%') >= 0; i++ {
+		var err error
+		if b, err = unescape(b); err != nil {
+			return nil
+		}
+	}
+
+	var layouts []Layout
+	add := func(codec string, decoded []byte, reasons ...string) {
+		layouts = append(layouts, newLayout(codec, escaped, decoded, now, reasons))
+	}
+
+	if decoded, err := hex.DecodeString(string(b)); err == nil {
+		add("hex", decoded, fmt.Sprintf("%d hexadecimal characters", len(b)))
+	}
+
+	trimmed := bytes.TrimRight(b, "=")
+	padding := len(b) - len(trimmed)
+	urlSafe := bytes.ContainsAny(trimmed, "-_")
+	standard := bytes.ContainsAny(trimmed, "+/")
+	if urlSafe && standard {
+		return rank(layouts)
+	}
+	if !standard {
+		if decoded, err := base64.RawURLEncoding.DecodeString(string(trimmed)); err == nil {
+			switch {
+			case padding == 0:
+				add("base64url", decoded, fmt.Sprintf("%d web safe base64 characters", len(trimmed)))
+			case isPadded(trimmed, padding):
+				add("base64url-padded", decoded, fmt.Sprintf("%d web safe base64 characters, padded", len(trimmed)))
+			}
+		}
+	}
+	if !urlSafe {
+		if decoded, err := base64.RawStdEncoding.DecodeString(string(trimmed)); err == nil && (standard || isPadded(trimmed, padding)) {
+			add("base64", decoded, fmt.Sprintf("%d standard base64 characters", len(trimmed)))
+		}
+	}
+	return rank(layouts)
+}
+
+// isPadded tells whether a base64 token has the padding it should have.
+func isPadded(trimmed []byte, padding int) bool {
+	return padding > 0 && (len(trimmed)+padding)%4 == 0 && padding < 3
+}
+
+// newLayout : Returns a layout scored from the decoded token, which is not
+// an encrypted price if its size is out of bounds.
+func newLayout(codec string, escaped bool, decoded []byte, now time.Time, reasons []string) Layout {
+	layout := Layout{Codec: codec, Escaped: escaped, DecodedSize: len(decoded), Reasons: reasons}
+	switch {
+	case len(decoded) == decodedSize:
+		layout.Score = 0.5
+		layout.Reasons = append(layout.Reasons, fmt.Sprintf("decodes to %d bytes: 16 bytes IV, 8 bytes price and 4 bytes signature", decodedSize))
+	case len(decoded) >= minDecodedSize && len(decoded) <= maxDecodedSize:
+		layout.Score = 0.2
+		layout.Reasons = append(layout.Reasons, fmt.Sprintf("decodes to %d bytes instead of %d: pad or signature size may be customized", len(decoded), decodedSize))
+	default:
+		layout.Reasons = append(layout.Reasons, fmt.Sprintf("decodes to %d bytes, out of [%d, %d]", len(decoded), minDecodedSize, maxDecodedSize))
+		return layout
+	}
+
+	if codec == "base64url" {
+		layout.Score += 0.2
+		layout.Reasons = append(layout.Reasons, "web safe base64 is the codec from specs")
+	} else {
+		layout.Score += 0.1
+	}
+	seconds := binary.BigEndian.Uint32(decoded[0:4])
+	micros := binary.BigEndian.Uint32(decoded[4:8])
+	ivTime := time.Unix(int64(seconds), int64(micros)*int64(time.Microsecond))
+	if micros < 1000000 && !ivTime.Before(minIVTime) && ivTime.Before(now.Add(24*time.Hour)) {
+		layout.IVTime = ivTime.UTC()
+		layout.Score += 0.2
+		layout.Reasons = append(layout.Reasons, fmt.Sprintf("IV starts with timestamp %s", layout.IVTime.Format(time.RFC3339)))
+	}
+	if escaped {
+		layout.Score -= 0.05
+		layout.Reasons = append(layout.Reasons, "percent-encoded, needing lenient decoding")
+	}
+	return layout
+}
+
+// rank : Returns the layouts that may be encrypted prices, the most likely first.
+func rank(layouts []Layout) []Layout {
+	kept := layouts[:0]
+	for _, layout := range layouts {
+		if layout.Score > 0 {
+			kept = append(kept, layout)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Score > kept[j].Score
+	})
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
@@ -0,0 +1,78 @@
+package doubleclick
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	// Setup:
+	// timestamped was encrypted at fixedTime, see TestEncryptNowIsReproducible.
+	timestamped := "ZZN9JQAKW_UAAQIDBAUGB7SCKZAXEYUKQWAfvw"
+	decoded, _ := base64.RawURLEncoding.DecodeString(timestamped)
+
+	tests := []struct {
+		name    string
+		token   string
+		codecs  []string
+		score   float64
+		escaped bool
+	}{
+		{"web safe base64", "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", []string{"base64url"}, 0.7, false},
+		{"timestamp IV", timestamped, []string{"base64url"}, 0.9, false},
+		{"padded", timestamped + "==", []string{"base64url-padded"}, 0.8, false},
+		{"standard base64", "ZZN9JQAKW/UAAQIDBAUGB7SCKZAXEYUKQWAfvw==", []string{"base64"}, 0.8, false},
+		{"hex", hex.EncodeToString(decoded), []string{"hex", "base64url"}, 0.8, false},
+		{"percent-encoded", "ZZN9JQAKW%2FUAAQIDBAUGB7SCKZAXEYUKQWAfvw%3D%3D", []string{"base64"}, 0.75, true},
+		{"custom signature size", base64.RawURLEncoding.EncodeToString(append(decoded, 0, 0, 0, 0)), []string{"base64url"}, 0.6, false},
+		{"mixed alphabets", "ZZN9JQAKW_UAAQIDBAUGB7SCKZAXEYUKQWAf+w", nil, 0, false},
+		{"too short", "too short", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute:
+			layouts := Analyze(tt.token)
+
+			// Verify:
+			var codecs []string
+			for _, layout := range layouts {
+				codecs = append(codecs, layout.Codec)
+			}
+			assert.Equal(t, tt.codecs, codecs)
+			if len(layouts) > 0 {
+				assert.InDelta(t, tt.score, layouts[0].Score, 1e-9, layouts[0].Reasons)
+				assert.Equal(t, tt.escaped, layouts[0].Escaped)
+			}
+		})
+	}
+}
+
+func TestAnalyzeIVTime(t *testing.T) {
+	// Execute:
+	layouts := Analyze("ZZN9JQAKW_UAAQIDBAUGB7SCKZAXEYUKQWAfvw")
+	spec := Analyze("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+
+	// Verify:
+	assert.Equal(t, fixedTime, layouts[0].IVTime)
+	assert.Contains(t, layouts[0].Reasons, "IV starts with timestamp 2024-01-02T03:04:05Z")
+	assert.Equal(t, 28, layouts[0].DecodedSize)
+	assert.True(t, spec[0].IVTime.IsZero())
+}
+
+func TestAnalyzeWithClock(t *testing.T) {
+	// Setup:
+	before := func() time.Time { return fixedTime.Add(-48 * time.Hour) }
+
+	// Execute:
+	layouts := AnalyzeWithClock("ZZN9JQAKW_UAAQIDBAUGB7SCKZAXEYUKQWAfvw", fixedClock)
+	future := AnalyzeWithClock("ZZN9JQAKW_UAAQIDBAUGB7SCKZAXEYUKQWAfvw", before)
+
+	// Verify:
+	assert.Equal(t, fixedTime, layouts[0].IVTime)
+	assert.True(t, future[0].IVTime.IsZero())
+	assert.Less(t, future[0].Score, layouts[0].Score)
+}
@@ -0,0 +1,120 @@
+package doubleclick
+
+import (
+	"hash"
+	"io"
+	"time"
+
+	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/logging"
+	"github.com/benjaminch/pricers/metrics"
+)
+
+// Option configures a DoubleClickPricer.
+type Option func(*DoubleClickPricer)
+
+// WithLogger makes the pricer emit structured debug events to logger,
+// for each step of the encryption and decryption pipeline.
+// Key material is redacted.
+func WithLogger(logger logging.Logger) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.logger = logger
+	}
+}
+
+// WithObserver makes the pricer report every Encrypt and Decrypt
+// outcome and latency to observer.
+func WithObserver(observer metrics.Observer) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.observer = observer
+	}
+}
+
+// WithExchange sets the exchange name reported in errors.
+func WithExchange(exchange string) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.exchange = exchange
+	}
+}
+
+// WithPriceLimits sets the range of prices the pricer accepts, a zero max
+// meaning no upper limit. Out of range prices fail with ErrOutOfRange.
+func WithPriceLimits(min float64, max float64) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.minPrice = min
+		dc.maxPrice = max
+	}
+}
+
+// WithForeignKeys adds keys the pricer tells encrypted prices were issued
+// for, e.g. the keys of other exchanges or retired keys. Encrypted prices
+// whose signature matches them rather than the pricer keys fail with
+// KindKeyMismatch instead of KindSignature. It can be given several times.
+// The keys are not copied, nor zeroed by Zero, see NewDoubleClickPricerWithKeys.
+func WithForeignKeys(encryptionKey *helpers.Key, integrityKey *helpers.Key) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.foreignKeys = append(dc.foreignKeys, keySet{encryptionKey: encryptionKey, integrityKey: integrityKey})
+	}
+}
+
+// Clock returns the current time.
+type Clock func() time.Time
+
+// WithClock sets the clock the IV timestamp of EncryptNow is read from,
+// time.Now by default. Latencies reported to observers are not affected.
+func WithClock(clock Clock) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.clock = clock
+	}
+}
+
+// WithRand sets the entropy source the IV random bytes of EncryptNow are
+// read from, crypto/rand by default.
+func WithRand(rand io.Reader) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.rand = rand
+	}
+}
+
+// WithHash sets the hash function HMACs are computed with, SHA-1 by
+// default, for exchanges using Google's construction with another hash.
+func WithHash(newHash func() hash.Hash) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.newHash = newHash
+	}
+}
+
+// WithPadSize sets the size the pad is truncated to, DefaultPadSize by
+// default. The encrypted price takes as many bytes, the scaled price being
+// right aligned. It should be between 8 and the hash size.
+func WithPadSize(size int) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.padSize = size
+	}
+}
+
+// WithSignatureSize sets the size the signature is truncated to,
+// DefaultSignatureSize by default. It should be between 4 and the hash size.
+func WithSignatureSize(size int) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.signatureSize = size
+	}
+}
+
+// WithCodec sets the codec encrypted prices are encoded with by Encrypt,
+// and expected to be encoded with by Decrypt, Base64URL by default.
+func WithCodec(codec Codec) Option {
+	return func(dc *DoubleClickPricer) {
+		dc.codec = codec
+	}
+}
+
+// WithLenientDecoding makes Decrypt accept encrypted prices encoded with
+// any codec, padded or not, and percent-encoded up to twice, as found in
+// notice URLs. Encrypted prices are normalized before being decoded, the
+// signature check being unaffected.
+func WithLenientDecoding() Option {
+	return func(dc *DoubleClickPricer) {
+		dc.lenient = true
+	}
+}
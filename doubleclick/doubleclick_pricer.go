@@ -2,33 +2,104 @@ package doubleclick
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"crypto/md5"
-	"encoding/base64"
+	"crypto/rand"
+	"crypto/sha1"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash"
+	"io"
+	"math"
 	"strings"
+	"time"
 
+	"github.com/benjaminch/pricers"
 	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/logging"
+	"github.com/benjaminch/pricers/metrics"
 )
 
-var ErrWrongSize = errors.New("Encrypted price is not 38 chars")
-var ErrWrongSignature = errors.New("Failed to decrypt")
-
 // DoubleClickPricer implementing price encryption and decryption
 // Specs : https://developers.google.com/ad-exchange/rtb/response-guide/decrypt-price
+// A DoubleClickPricer is safe for concurrent use.
 type DoubleClickPricer struct {
-	encryptionKeyRaw string
-	integrityKeyRaw  string
-	encryptionKey    hash.Hash
-	integrityKey     hash.Hash
-	keyDecodingMode  helpers.KeyDecodingMode
-	scaleFactor      float64
-	isDebugMode      bool
+	encryptionKey *helpers.Key
+	integrityKey  *helpers.Key
+	exchange      string
+	scaleFactor   float64
+	minPrice      float64
+	maxPrice      float64
+	isDebugMode   bool
+	logger        logging.Logger
+	observer      metrics.Observer
+	clock         Clock
+	rand          io.Reader
+	newHash       func() hash.Hash
+	padSize       int
+	signatureSize int
+	codec         Codec
+	lenient       bool
+	decodedLen    int
+	encodedLen    int
+	foreignKeys   []keySet
+	states        statePool
+}
+
+// keySet holds the keys of another pricer, see WithForeignKeys.
+type keySet struct {
+	encryptionKey *helpers.Key
+	integrityKey  *helpers.Key
+}
+
+// hmacState holds HMAC functions with their precomputed inner and outer
+// state, along with scratch buffers, so that a call doesn't allocate.
+// It is not safe for concurrent use, hence pooled.
+type hmacState struct {
+	encryptionKey *keyedHMAC
+	integrityKey  *keyedHMAC
+	pad           []byte
+	signature     []byte
+	iv            [ivSize]byte
+	// data holds the scaled price, big endian, on padSize bytes.
+	data []byte
+	// message holds iv || enc_price || signature.
+	message []byte
+	// token holds the encrypted price normalized by lenient decoding.
+	token []byte
+	// codec is the codec the last encrypted price was decoded with.
+	codec Codec
+}
+
+// scaledPrice : Returns the scaled price held by the state.
+func (state *hmacState) scaledPrice() uint64 {
+	return binary.BigEndian.Uint64(state.data[len(state.data)-8:])
 }
 
+const (
+	// ivSize is the size of the initialization vector.
+	ivSize = 16
+	// priceSize is the size of the scaled price.
+	priceSize = 8
+	// DefaultPadSize is the size of the pad, and of the encrypted price, from specs.
+	DefaultPadSize = 8
+	// DefaultSignatureSize is the size of the signature from specs.
+	DefaultSignatureSize = 4
+	// minSignatureSize is the size signatures can't be truncated below.
+	minSignatureSize = 4
+	// encodedSize is the size of a web safe base64 encoded encrypted price, from specs.
+	encodedSize = 38
+	// decodedSize is the size of iv || enc_price || signature, from specs.
+	decodedSize = ivSize + DefaultPadSize + DefaultSignatureSize
+)
+
+// Protocol is the protocol name reported to observers.
+const Protocol = "doubleclick"
+
+var _ pricers.Pricer = (*DoubleClickPricer)(nil)
+
 // NewDoubleClickPricer returns a DoubleClickPricer struct.
 // Keys are either base 64 websafe of hexa. keyDecodingMode
 // should be used to specify how keys should be decoded.
@@ -36,138 +107,332 @@ type DoubleClickPricer struct {
 // from specs, scaleFactor is 1,000,000, but you can set something else.
 // Be aware that the price is stored as an int64 so depending on the digits
 // precision you want, picking a scale factor smaller than 1,000,000 may lead
-// to price to be rounded and loose some digits precision.
+// to price to be rounded and loose some digits precision. It must be positive
+// and finite, an error being returned otherwise.
+// Debug events are emitted to the logger set with WithLogger. If none is set
+// and isDebugMode is true, they are written as text to stderr.
 func NewDoubleClickPricer(
 	encryptionKey string,
 	integrityKey string,
 	isBase64Keys bool,
 	keyDecodingMode helpers.KeyDecodingMode,
 	scaleFactor float64,
-	isDebugMode bool) (*DoubleClickPricer, error) {
-	var err error
-	var encryptingFun, integrityFun hash.Hash
-
-	encryptingFun, err = helpers.CreateHmac(encryptionKey, isBase64Keys, keyDecodingMode)
+	isDebugMode bool,
+	options ...Option) (*DoubleClickPricer, error) {
+	encryptionKeyDecoded, err := helpers.ParseKey(encryptionKey, isBase64Keys, keyDecodingMode)
 	if err != nil {
 		return nil, err
 	}
-	integrityFun, err = helpers.CreateHmac(integrityKey, isBase64Keys, keyDecodingMode)
+	integrityKeyDecoded, err := helpers.ParseKey(integrityKey, isBase64Keys, keyDecodingMode)
 	if err != nil {
 		return nil, err
 	}
 
-	if isDebugMode {
-		fmt.Println("Keys decoding mode : ", keyDecodingMode)
-		fmt.Println("Encryption key : ", encryptionKey)
-		encryptionKeyHexa, err := hex.DecodeString(encryptionKey)
-		if err != nil {
-			encryptionKeyHexa = []byte(encryptionKey)
-		}
-		fmt.Println("Encryption key (bytes) : ", []byte(encryptionKeyHexa))
-		fmt.Println("Integrity key : ", integrityKey)
-		integrityKeyHexa, err := hex.DecodeString(integrityKey)
-		if err != nil {
-			integrityKeyHexa = []byte(integrityKey)
-		}
-		fmt.Println("Integrity key (bytes) : ", []byte(integrityKeyHexa))
+	return newDoubleClickPricer(encryptionKeyDecoded, integrityKeyDecoded, scaleFactor, isDebugMode, options,
+		logging.F("key_decoding_mode", keyDecodingMode.String()),
+		logging.F("is_base64_keys", isBase64Keys))
+}
+
+// NewDoubleClickPricerWithKeys returns a DoubleClickPricer struct using
+// already decoded keys, e.g. loaded from a key file. See NewDoubleClickPricer.
+// The pricer doesn't copy the keys, which must not be zeroed while it is in use, see Zero.
+func NewDoubleClickPricerWithKeys(
+	encryptionKey *helpers.Key,
+	integrityKey *helpers.Key,
+	scaleFactor float64,
+	isDebugMode bool,
+	options ...Option) (*DoubleClickPricer, error) {
+	return newDoubleClickPricer(encryptionKey, integrityKey, scaleFactor, isDebugMode, options)
+}
+
+// newDoubleClickPricer returns a DoubleClickPricer struct, the pricer
+// created debug event holding the given fields along with the keys and scale factor.
+func newDoubleClickPricer(
+	encryptionKey *helpers.Key,
+	integrityKey *helpers.Key,
+	scaleFactor float64,
+	isDebugMode bool,
+	options []Option,
+	fields ...logging.Field) (*DoubleClickPricer, error) {
+	// Amounts are exact fractions of the scaled price, see DecryptAmount.
+	if !(scaleFactor > 0) || math.IsInf(scaleFactor, 1) {
+		return nil, fmt.Errorf("doubleclick: scale factor %g should be positive and finite", scaleFactor)
+	}
+	dc := &DoubleClickPricer{
+		encryptionKey: encryptionKey,
+		integrityKey:  integrityKey,
+		scaleFactor:   scaleFactor,
+		isDebugMode:   isDebugMode,
+		clock:         time.Now,
+		rand:          rand.Reader,
+		newHash:       sha1.New,
+		padSize:       DefaultPadSize,
+		signatureSize: DefaultSignatureSize,
+		codec:         Base64URL}
+	for _, option := range options {
+		option(dc)
+	}
+	if size := dc.newHash().Size(); dc.padSize < priceSize || dc.padSize > size {
+		return nil, fmt.Errorf("doubleclick: pad size %d should be between %d and the hash size %d", dc.padSize, priceSize, size)
+	} else if dc.signatureSize < minSignatureSize || dc.signatureSize > size {
+		return nil, fmt.Errorf("doubleclick: signature size %d should be between %d and the hash size %d", dc.signatureSize, minSignatureSize, size)
+	}
+	dc.decodedLen = ivSize + dc.padSize + dc.signatureSize
+	dc.encodedLen = dc.codec.EncodedLen(dc.decodedLen)
+	if dc.logger == nil && isDebugMode {
+		dc.logger = logging.NewStderrLogger()
+	}
+
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: pricer created", append(fields,
+			logging.F("encryption_key", encryptionKey),
+			logging.F("integrity_key", integrityKey),
+			logging.F("scale_factor", scaleFactor))...)
 	}
 
-	return &DoubleClickPricer{
-			encryptionKeyRaw: encryptionKey,
-			integrityKeyRaw:  integrityKey,
-			encryptionKey:    encryptingFun,
-			integrityKey:     integrityFun,
-			keyDecodingMode:  keyDecodingMode,
-			scaleFactor:      scaleFactor,
-			isDebugMode:      isDebugMode},
-		nil
+	return dc, nil
 }
 
 // Encrypt encrypts a clear price and a given seed.
+// ErrOutOfRange is returned if the scaled price doesn't fit 63 bits
+// or is out of the limits set with WithPriceLimits.
 func (dc *DoubleClickPricer) Encrypt(seed string, price float64) (string, error) {
-	var (
-		iv        [16]byte
-		encoded   [8]byte
-		signature []byte
-	)
+	encrypted, err := dc.AppendEncrypt(make([]byte, 0, dc.encodedLen), []byte(seed), price)
+	if err != nil {
+		return "", err
+	}
+	return string(encrypted), nil
+}
 
-	data := helpers.ApplyScaleFactor(price, dc.scaleFactor, dc.isDebugMode)
+// AppendEncrypt encrypts a clear price and a given seed, appends the
+// encrypted price to dst and returns the extended buffer.
+// It doesn't allocate when dst has enough capacity, see EncodedSize.
+func (dc *DoubleClickPricer) AppendEncrypt(dst []byte, seed []byte, price float64) (_ []byte, err error) {
+	if dc.observer != nil {
+		start := time.Now()
+		defer func() { dc.observe(metrics.Encrypt, start, err) }()
+	}
+
+	if !dc.canEncrypt(price) {
+		return dst, ErrOutOfRange
+	}
+
+	state := dc.getState()
+	defer dc.states.put(state)
+	dc.setScaledPrice(state, price)
 
 	// Create Initialization Vector from seed
-	iv = md5.Sum([]byte(seed))
-	if dc.isDebugMode {
-		fmt.Println("Seed : ", seed)
-		fmt.Println("Initialization vector : ", iv)
+	state.iv = md5.Sum(seed)
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: encrypt iv",
+			logging.F("seed", string(seed)),
+			logging.F("iv", hex.EncodeToString(state.iv[:])))
+	}
+
+	return dc.appendEncrypt(dst, state), nil
+}
+
+// EncryptNow encrypts a clear price with a Google-style IV: the time read
+// from the clock, as big endian seconds and microseconds since the Unix
+// epoch, followed by 8 random bytes.
+// ErrOutOfRange is returned as Encrypt does.
+func (dc *DoubleClickPricer) EncryptNow(price float64) (string, error) {
+	encrypted, err := dc.AppendEncryptNow(make([]byte, 0, dc.encodedLen), price)
+	if err != nil {
+		return "", err
+	}
+	return string(encrypted), nil
+}
+
+// AppendEncryptNow encrypts a clear price as EncryptNow does, appends the
+// encrypted price to dst and returns the extended buffer.
+func (dc *DoubleClickPricer) AppendEncryptNow(dst []byte, price float64) (_ []byte, err error) {
+	if dc.observer != nil {
+		start := time.Now()
+		defer func() { dc.observe(metrics.Encrypt, start, err) }()
+	}
+
+	if !dc.canEncrypt(price) {
+		return dst, ErrOutOfRange
+	}
+
+	state := dc.getState()
+	defer dc.states.put(state)
+	dc.setScaledPrice(state, price)
+
+	now := dc.clock()
+	binary.BigEndian.PutUint32(state.iv[0:4], uint32(now.Unix()))
+	binary.BigEndian.PutUint32(state.iv[4:8], uint32(now.Nanosecond()/int(time.Microsecond)))
+	if _, err := io.ReadFull(dc.rand, state.iv[8:16]); err != nil {
+		return dst, err
 	}
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: encrypt iv",
+			logging.F("time", now),
+			logging.F("iv", hex.EncodeToString(state.iv[:])))
+	}
+
+	return dc.appendEncrypt(dst, state), nil
+}
+
+// canEncrypt tells whether the scaled price fits 63 bits and the price is
+// within the limits set with WithPriceLimits.
+func (dc *DoubleClickPricer) canEncrypt(price float64) bool {
+	scaled := price * dc.scaleFactor
+	return scaled >= 0 && scaled < math.MaxInt64 && dc.inLimits(price)
+}
+
+// setScaledPrice sets the scaled price held by state.
+func (dc *DoubleClickPricer) setScaledPrice(state *hmacState, price float64) {
+	scaled := helpers.ApplyScaleFactorWithLogger(price, dc.scaleFactor, dc.logger)
+	clear(state.data[:dc.padSize-priceSize])
+	copy(state.data[dc.padSize-priceSize:], scaled[:])
+}
+
+// EncodedSize : Returns the size of the encrypted prices of the pricer,
+// 38 chars with the default hash and sizes.
+func (dc *DoubleClickPricer) EncodedSize() int {
+	return dc.encodedLen
+}
+
+// Exchange : Returns the exchange name set with WithExchange, empty if none.
+func (dc *DoubleClickPricer) Exchange() string {
+	return dc.exchange
+}
+
+// appendEncrypt encrypts the scaled price with the IV, both held by state,
+// and appends the encrypted price to dst.
+func (dc *DoubleClickPricer) appendEncrypt(dst []byte, state *hmacState) []byte {
+	data := state.data
+	iv := state.iv[:]
 
 	//pad = hmac(e_key, iv), first 8 bytes
-	pad := helpers.HmacSum(dc.encryptionKey, iv[:], nil)[:8]
-	if dc.isDebugMode {
-		fmt.Println("// pad = hmac(e_key, iv), first 8 bytes")
-		fmt.Println("Pad : ", pad)
+	pad := helpers.AppendHmacSum(state.pad[:0], state.encryptionKey, iv, nil)[:dc.padSize]
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: encrypt pad", logging.F("pad", hex.EncodeToString(pad)))
 	}
 
 	// signature = hmac(i_key, data || iv), first 4 bytes
-	signature = helpers.HmacSum(dc.integrityKey, data[:], iv[:])[:4]
-	if dc.isDebugMode {
-		fmt.Println("// signature = hmac(i_key, data || iv), first 4 bytes")
-		fmt.Println("Signature : ", signature)
+	signature := helpers.AppendHmacSum(state.signature[:0], state.integrityKey, data, iv)[:dc.signatureSize]
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: encrypt signature", logging.F("signature", hex.EncodeToString(signature)))
 	}
 
 	// enc_data = pad <xor> data
+	message := state.message
+	encoded := message[ivSize : ivSize+dc.padSize]
 	for i := range data {
 		encoded[i] = pad[i] ^ data[i]
 	}
-	if dc.isDebugMode {
-		fmt.Println("// enc_data = pad <xor> data")
-		fmt.Println("Encoded price bytes : ", encoded)
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: encrypt price", logging.F("encoded_price", hex.EncodeToString(encoded)))
 	}
 
-	// final_message = WebSafeBase64Encode( iv || enc_price || signature )
-	return base64.RawURLEncoding.EncodeToString(append(append(iv[:], encoded[:]...), signature...)), nil
+	// final_message = Encode( iv || enc_price || signature ), web safe base64 by default
+	copy(message[0:ivSize], iv)
+	copy(message[ivSize+dc.padSize:], signature)
+
+	n := len(dst)
+	if cap(dst)-n < dc.encodedLen {
+		grown := make([]byte, n, n+dc.encodedLen)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:n+dc.encodedLen]
+	dc.codec.Encode(dst[n:], message)
+
+	return dst
 }
 
 // Decrypt decrypts an encrypted price.
+// Errors are *DecryptError, matching ErrWrongSize, ErrWrongEncoding,
+// ErrWrongSignature or ErrOutOfRange with errors.Is.
 func (dc *DoubleClickPricer) Decrypt(encryptedPrice string) (float64, error) {
-	var err error
-	var errPrice float64
+	state := dc.getState()
+	defer dc.states.put(state)
 
-	// Decode base64 url
+	return dc.decryptString(state, encryptedPrice)
+}
+
+// DecryptWithTime decrypts an encrypted price and returns the time carried
+// by its IV, for prices encrypted with EncryptNow or by exchanges following
+// the same IV layout. Errors are the ones of Decrypt.
+func (dc *DoubleClickPricer) DecryptWithTime(encryptedPrice string) (float64, time.Time, error) {
+	state := dc.getState()
+	defer dc.states.put(state)
+
+	price, err := dc.decryptString(state, encryptedPrice)
+	if err != nil {
+		return price, time.Time{}, err
+	}
+	iv := state.message[0:ivSize]
+	seconds := binary.BigEndian.Uint32(iv[0:4])
+	micros := binary.BigEndian.Uint32(iv[4:8])
+	return price, time.Unix(int64(seconds), int64(micros)*int64(time.Microsecond)), nil
+}
+
+// decryptString decrypts an encrypted price held in a string using the given state.
+func (dc *DoubleClickPricer) decryptString(state *hmacState, encryptedPrice string) (float64, error) {
 	// Just to be safe remove padding if it was added by mistake
-	encryptedPrice = strings.TrimRight(encryptedPrice, "=")
-	if len(encryptedPrice) != 38 {
-		return errPrice, ErrWrongSize
+	if dc.codec == Base64URL && !dc.lenient {
+		encryptedPrice = strings.TrimRight(encryptedPrice, "=")
 	}
-	decoded, err := base64.RawURLEncoding.DecodeString(encryptedPrice)
-	if err != nil {
-		return errPrice, err
+	// Size is checked before converting, unless the price has to be normalized.
+	if (!dc.lenient && len(encryptedPrice) != dc.encodedLen) || len(encryptedPrice) > dc.maxLenientLen() {
+		err := dc.newDecryptError(KindSize, []byte(encryptedPrice), dc.sizeError(len(encryptedPrice)))
+		if dc.observer != nil {
+			dc.observe(metrics.Decrypt, time.Now(), err)
+		}
+		return 0, err
 	}
+	return dc.decrypt(state, []byte(encryptedPrice))
+}
 
-	if dc.isDebugMode {
-		fmt.Println("Encrypted price : ", encryptedPrice)
-		fmt.Println("Base64 decoded price : ", decoded)
+// DecryptBytes decrypts an encrypted price held in a byte slice.
+// It doesn't allocate on success.
+func (dc *DoubleClickPricer) DecryptBytes(encryptedPrice []byte) (float64, error) {
+	state := dc.getState()
+	defer dc.states.put(state)
+
+	return dc.decrypt(state, encryptedPrice)
+}
+
+// decrypt decrypts an encrypted price using the given state,
+// reporting to the observer if any.
+func (dc *DoubleClickPricer) decrypt(state *hmacState, encryptedPrice []byte) (float64, error) {
+	if dc.observer == nil {
+		return dc.decryptPrice(state, encryptedPrice)
 	}
+	start := time.Now()
+	price, err := dc.decryptPrice(state, encryptedPrice)
+	dc.observe(metrics.Decrypt, start, err)
+	return price, err
+}
 
-	// Get elements
-	var (
-		iv         []byte
-		p          []byte
-		signature  []byte
-		priceMicro [8]byte
-	)
+// decryptPrice decrypts an encrypted price using the given state.
+func (dc *DoubleClickPricer) decryptPrice(state *hmacState, encryptedPrice []byte) (float64, error) {
+	var errPrice float64
+
+	decoded, err := dc.decodeToken(state, encryptedPrice)
+	if err != nil {
+		return errPrice, err
+	}
 
-	iv = decoded[0:16]
-	p = decoded[16:24]
-	signature = decoded[24:28]
+	// Get elements
+	iv := decoded[0:ivSize]
+	p := decoded[ivSize : ivSize+dc.padSize]
+	signature := decoded[ivSize+dc.padSize:]
+	priceMicro := state.data
 
 	// pad = hmac(e_key, iv)
-	pad := helpers.HmacSum(dc.encryptionKey, iv, nil)[:8]
+	pad := helpers.AppendHmacSum(state.pad[:0], state.encryptionKey, iv, nil)[:dc.padSize]
 
-	if dc.isDebugMode {
-		fmt.Println("IV : ", hex.EncodeToString(iv))
-		fmt.Println("Encoded price : ", hex.EncodeToString(p))
-		fmt.Println("Signature : ", hex.EncodeToString(signature))
-		fmt.Println("Pad : ", hex.EncodeToString(pad))
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: decrypt iv",
+			logging.F("encrypted_price", string(encryptedPrice)),
+			logging.F("iv", hex.EncodeToString(iv)),
+			logging.F("encoded_price", hex.EncodeToString(p)))
+		dc.logger.Debug("doubleclick: decrypt pad", logging.F("pad", hex.EncodeToString(pad)))
 	}
 
 	// priceMicro = p <xor> pad
@@ -176,13 +441,126 @@ func (dc *DoubleClickPricer) Decrypt(encryptedPrice string) (float64, error) {
 	}
 
 	// conf_sig = hmac(i_key, data || iv)
-	confirmationSignature := helpers.HmacSum(dc.integrityKey, priceMicro[:], iv)[:4]
+	confirmationSignature := helpers.AppendHmacSum(state.signature[:0], state.integrityKey, priceMicro, iv)[:dc.signatureSize]
+
+	if dc.logger != nil {
+		dc.logger.Debug("doubleclick: decrypt signature",
+			logging.F("signature", hex.EncodeToString(signature)),
+			logging.F("confirmation_signature", hex.EncodeToString(confirmationSignature)))
+	}
 
 	// success = (conf_sig == sig)
 	if !bytes.Equal(confirmationSignature, signature) {
-		return errPrice, ErrWrongSignature
+		if dc.signedWithForeignKeys(iv, p, signature) {
+			return errPrice, dc.newDecryptError(KindKeyMismatch, encryptedPrice, nil)
+		}
+		return errPrice, dc.newDecryptError(KindSignature, encryptedPrice, nil)
+	}
+	// With a pad longer than the price, the leading bytes must be zero.
+	micros := state.scaledPrice()
+	if micros > math.MaxInt64 || !isZero(priceMicro[:dc.padSize-priceSize]) {
+		return errPrice, dc.newDecryptError(KindRange, encryptedPrice, nil)
+	}
+	price := float64(micros) / dc.scaleFactor
+	if !dc.inLimits(price) {
+		return errPrice, dc.newDecryptError(KindRange, encryptedPrice, nil)
 	}
-	price := float64(binary.BigEndian.Uint64(priceMicro[:])) / dc.scaleFactor
 
 	return price, nil
 }
+
+// signedWithForeignKeys tells whether the signature of an encrypted price
+// matches one of the key sets set with WithForeignKeys.
+func (dc *DoubleClickPricer) signedWithForeignKeys(iv []byte, encryptedPrice []byte, signature []byte) bool {
+	for _, keys := range dc.foreignKeys {
+		pad := helpers.HmacSum(hmac.New(dc.newHash, keys.encryptionKey.Bytes()), iv, nil)[:dc.padSize]
+		price := make([]byte, dc.padSize)
+		for i := range price {
+			price[i] = pad[i] ^ encryptedPrice[i]
+		}
+		expected := helpers.HmacSum(hmac.New(dc.newHash, keys.integrityKey.Bytes()), price, iv)[:dc.signatureSize]
+		if hmac.Equal(expected, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// inLimits tells whether price is within the limits set with WithPriceLimits.
+func (dc *DoubleClickPricer) inLimits(price float64) bool {
+	return price >= dc.minPrice && (dc.maxPrice == 0 || price <= dc.maxPrice)
+}
+
+// observe reports an operation started at start and ended with err to the observer.
+func (dc *DoubleClickPricer) observe(operation metrics.Operation, start time.Time, err error) {
+	dc.observer.Observe(metrics.Event{
+		Protocol:  Protocol,
+		Exchange:  dc.exchange,
+		Operation: operation,
+		Outcome:   OutcomeOf(err),
+		Latency:   time.Since(start),
+	})
+}
+
+// OutcomeOf returns the metrics outcome matching an Encrypt or Decrypt error.
+func OutcomeOf(err error) metrics.Outcome {
+	if err == nil {
+		return metrics.OK
+	}
+	switch KindOf(err) {
+	case KindSize:
+		return metrics.WrongSize
+	case KindEncoding:
+		return metrics.BadBase64
+	case KindSignature:
+		return metrics.WrongSignature
+	case KindKeyMismatch:
+		return metrics.KeyMismatch
+	case KindRange:
+		return metrics.OutOfRange
+	}
+	if errors.Is(err, ErrOutOfRange) {
+		return metrics.OutOfRange
+	}
+	return metrics.Error
+}
+
+// Zero retires the pricer: its keys are zeroed, and so are the HMAC states
+// derived from them, along with their buffers. States in use by concurrent
+// calls are zeroed when these calls return.
+// The pricer must not be used afterwards.
+func (dc *DoubleClickPricer) Zero() {
+	dc.encryptionKey.Zero()
+	dc.integrityKey.Zero()
+	dc.states.zero()
+}
+
+// getState returns HMAC state from the pool, creating it if needed.
+func (dc *DoubleClickPricer) getState() *hmacState {
+	if state := dc.states.get(); state != nil {
+		return state
+	}
+	encryptionKey := newKeyedHMAC(dc.newHash, dc.encryptionKey.Bytes())
+	return &hmacState{
+		encryptionKey: encryptionKey,
+		integrityKey:  newKeyedHMAC(dc.newHash, dc.integrityKey.Bytes()),
+		pad:           make([]byte, 0, encryptionKey.Size()),
+		signature:     make([]byte, 0, encryptionKey.Size()),
+		data:          make([]byte, dc.padSize),
+		message:       make([]byte, dc.decodedLen),
+	}
+}
+
+// sizeError : Returns the cause of a KindSize error for an encrypted price of size chars.
+func (dc *DoubleClickPricer) sizeError(size int) error {
+	return fmt.Errorf("%d chars instead of %d", size, dc.encodedLen)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
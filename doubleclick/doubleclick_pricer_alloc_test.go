@@ -0,0 +1,43 @@
+package doubleclick
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func TestAppendEncryptDecryptBytesDoNotAllocate(t *testing.T) {
+	// Setup:
+	var pricer *DoubleClickPricer
+	var err error
+	pricer, err = buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	seed := []byte("seed")
+	encrypted := make([]byte, 0, 38)
+
+	// Execute:
+	encryptAllocs := testing.AllocsPerRun(100, func() {
+		encrypted, err = pricer.AppendEncrypt(encrypted[:0], seed, 1.354)
+	})
+	var decrypted float64
+	decryptAllocs := testing.AllocsPerRun(100, func() {
+		decrypted, err = pricer.DecryptBytes(encrypted)
+	})
+
+	// Verify:
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, decrypted, 0.001)
+	assert.Equal(t, float64(0), encryptAllocs, "AppendEncrypt should not allocate")
+	assert.Equal(t, float64(0), decryptAllocs, "DecryptBytes should not allocate")
+}
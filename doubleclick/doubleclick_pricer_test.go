@@ -1,15 +1,19 @@
 package doubleclick
 
 import (
+	"encoding/hex"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/logging"
+	"github.com/benjaminch/pricers/testvectors"
 )
 
-func buildNewDoubleClickPricer(encryptionKey string, integrityKey string, isBase64Keys bool, keyDecodingMode helpers.KeyDecodingMode, scaleFactor float64, isDebugMode bool) (*DoubleClickPricer, error) {
-	return NewDoubleClickPricer(encryptionKey, integrityKey, isBase64Keys, keyDecodingMode, scaleFactor, isDebugMode)
+func buildNewDoubleClickPricer(encryptionKey string, integrityKey string, isBase64Keys bool, keyDecodingMode helpers.KeyDecodingMode, scaleFactor float64, isDebugMode bool, options ...Option) (*DoubleClickPricer, error) {
+	return NewDoubleClickPricer(encryptionKey, integrityKey, isBase64Keys, keyDecodingMode, scaleFactor, isDebugMode, options...)
 }
 
 type priceTestCase struct {
@@ -39,7 +43,7 @@ func TestDecryptEmpty(t *testing.T) {
 	var result float64
 	result, err = pricer.Decrypt("")
 	// Verify:
-	assert.Equal(t, err, ErrWrongSize)
+	assert.ErrorIs(t, err, ErrWrongSize)
 	assert.Equal(t, float64(0), result)
 }
 
@@ -204,8 +208,133 @@ func TestDecryptWithScaleFactor(t *testing.T) {
 	}
 }
 
+// recordingLogger records debug events.
+type recordingLogger struct {
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	msg    string
+	fields map[string]interface{}
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...logging.Field) {
+	event := recordedEvent{msg: msg, fields: map[string]interface{}{}}
+	for _, field := range fields {
+		event.fields[field.Key] = field.Value
+	}
+	l.events = append(l.events, event)
+}
+
+func (l *recordingLogger) messages() []string {
+	messages := make([]string, len(l.events))
+	for i, event := range l.events {
+		messages[i] = event.msg
+	}
+	return messages
+}
+
+func TestNewWithDebugRedactsKeys(t *testing.T) {
+	// Setup:
+	logger := &recordingLogger{}
+
+	// Execute:
+	_, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		true,
+		WithLogger(logger),
+	)
+
+	// Verify:
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	assert.Equal(t, []string{"doubleclick: pricer created"}, logger.messages())
+	fields := logger.events[0].fields
+	assert.Equal(t, "hexa", fields["key_decoding_mode"])
+	assert.Equal(t, "[REDACTED 32 bytes, fingerprint 06ae61]", fmt.Sprint(fields["encryption_key"]))
+	assert.Equal(t, "[REDACTED 32 bytes, fingerprint 8aa8d3]", fmt.Sprint(fields["integrity_key"]))
+}
+
+func TestNewWithKeys(t *testing.T) {
+	// Setup:
+	encryptionKey, err := helpers.ParseKey("652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135", false, helpers.Hexa)
+	assert.Nil(t, err)
+	integrityKey, err := helpers.ParseKey("bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5", false, helpers.Hexa)
+	assert.Nil(t, err)
+	pricer, err := NewDoubleClickPricerWithKeys(encryptionKey, integrityKey, 1000000, false)
+	assert.Nil(t, err)
+
+	// Execute:
+	result, err := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result)
+}
+
+func TestZero(t *testing.T) {
+	// Setup:
+	encryptionKey, err := helpers.ParseKey("652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135", false, helpers.Hexa)
+	assert.Nil(t, err)
+	integrityKey, err := helpers.ParseKey("bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5", false, helpers.Hexa)
+	assert.Nil(t, err)
+	encryptionKeyBytes := encryptionKey.Bytes()
+	pricer, err := NewDoubleClickPricerWithKeys(encryptionKey, integrityKey, 1000000, false)
+	assert.Nil(t, err)
+	_, err = pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+	assert.Nil(t, err)
+	pooled := pricer.getState()
+	inUse := pricer.getState()
+	pricer.states.put(pooled)
+
+	// Execute:
+	pricer.Zero()
+	pricer.states.put(inUse)
+
+	// Verify:
+	assert.Equal(t, make([]byte, 32), encryptionKeyBytes)
+	assert.Equal(t, 0, encryptionKey.Len())
+	assert.Equal(t, 0, integrityKey.Len())
+	for _, state := range []*hmacState{pooled, inUse} {
+		for _, h := range []*keyedHMAC{state.encryptionKey, state.integrityKey} {
+			assert.True(t, isZero(h.ipad))
+			assert.True(t, isZero(h.opad))
+		}
+		assert.True(t, isZero(state.message))
+	}
+	assert.Nil(t, pricer.states.get())
+}
+
 func TestDecryptWithDebug(t *testing.T) {
-	// TODO: To be implemented
+	// Setup:
+	logger := &recordingLogger{}
+	var pricer *DoubleClickPricer
+	var err error
+	pricer, err = buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		true,
+		WithLogger(logger),
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	logger.events = nil
+
+	// Execute:
+	var result float64
+	result, err = pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+	assert.Equal(t, []string{"doubleclick: decrypt iv", "doubleclick: decrypt pad", "doubleclick: decrypt signature"}, logger.messages())
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", logger.events[0].fields["iv"])
+	assert.Equal(t, logger.events[2].fields["signature"], logger.events[2].fields["confirmation_signature"])
 }
 
 func TestEncryptWithHexaKeys(t *testing.T) {
@@ -250,50 +379,44 @@ func TestEncryptWithHexaKeys(t *testing.T) {
 	}
 }
 
-/*
 func TestEncryptWithUtf8Keys(t *testing.T) {
-	// Create a pricer with:
+	// Create pricers with:
 	// - UTF-8 keys
 	// - Price scale factor as micro
 	// - No debug mode
-	var pricer *DoubleClickPricer
-	var err error
-	pricer, err = buildNewDoubleClickPricer(
-		"ZS_Cg8KtwqBUUVfCocK3w7sMDgnDtcKeczczL8OnwqvDlMOrEETCm8KOw6bDg8KRNQ",
-		"wr0KPcO7woLCrcKVw4XDpj4Vwppiw7c8asOKwpjCuiTClTIhwpR1wp1RLXfDqyvCtQ",
-		false, // Keys are base64
-		helpers.Utf8,
-		1000000,
-		false,
-	)
-
-	if err != nil {
-		t.Error("Error creating new Pricer : ", err)
-	}
+	// The keys are the raw bytes of the hexa keys of the empty seed test
+	// vectors, used as is, so they encrypt to the same prices.
+	// They used to be given base64 encoded after a Latin-1 to UTF-8
+	// conversion, which mangles every byte above 0x7F.
+	for _, v := range testvectors.ForProtocol(Protocol) {
+		if v.KeyDecodingMode != helpers.Hexa || !v.CanEncrypt() || *v.Seed != "" || v.ScaleFactor != 1000000 {
+			continue
+		}
+		t.Run(v.Name, func(t *testing.T) {
+			// Setup:
+			encryptionKey, err := hex.DecodeString(v.EncryptionKey)
+			assert.Nil(t, err)
+			integrityKey, err := hex.DecodeString(v.IntegrityKey)
+			assert.Nil(t, err)
+			pricer, err := buildNewDoubleClickPricer(
+				string(encryptionKey),
+				string(integrityKey),
+				false, // Keys are not base64
+				helpers.Utf8,
+				v.ScaleFactor,
+				false,
+			)
+			assert.Nil(t, err, "Error creating new Pricer : ", err)
 
-	// Clear prices we will try to encrypt
-	var pricesTestCase = []priceTestCase{
-		newPriceTestCase("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA==", 1.354, 1000000),
-		newPriceTestCase("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGTyiewYLbwg==", 3.24, 1000000),
-		newPriceTestCase("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGcRqedwjz2g==", 1, 1000000),
-		newPriceTestCase("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGc8xOTOXIGA==", 0.89, 1000000),
-		newPriceTestCase("1B2M2Y8AsgTpgAmY7PhCfgDo9mJDi7nevR9kUw==", 100, 1000000),
-		newPriceTestCase("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGfn_O2Zdh_g==", 0.01, 1000000),
-	}
+			// Execute:
+			result, err := pricer.Encrypt(*v.Seed, v.Clear)
 
-	for _, price := range pricesTestCase {
-		var result string
-		var err error
-		result, err = pricer.Encrypt("", price.clear)
-		if err != nil {
-			t.Errorf("Encryption failed. Error : %s", err)
-		}
-		if result != price.encrypted {
-			t.Errorf("Encryption failed. Should be : %s but was : %s", price.encrypted, result)
-		}
+			// Verify:
+			assert.Nil(t, err, "Encryption failed. Error : %s", err)
+			assert.Equal(t, v.Encrypted, result, "Encryption failed. Should be : %s but was : %s", v.Encrypted, result)
+		})
 	}
 }
-*/
 
 func TestEncryptWithScaleFactor(t *testing.T) {
 
@@ -339,7 +462,32 @@ func TestEncryptWithScaleFactor(t *testing.T) {
 }
 
 func TestEncryptWithDebug(t *testing.T) {
-	// TODO : To be implemented
+	// Setup:
+	logger := &recordingLogger{}
+	var pricer *DoubleClickPricer
+	var err error
+	pricer, err = buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+		WithLogger(logger),
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	logger.events = nil
+
+	// Execute:
+	var result string
+	result, err = pricer.Encrypt("", 1.354)
+
+	// Verify:
+	assert.Nil(t, err, "Encryption failed. Error : %s", err)
+	assert.Equal(t, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", result)
+	assert.Equal(t, []string{"helpers: scale factor applied", "doubleclick: encrypt iv", "doubleclick: encrypt pad", "doubleclick: encrypt signature", "doubleclick: encrypt price"}, logger.messages())
+	assert.Equal(t, "000000000014a910", logger.events[0].fields["scaled_price"])
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", logger.events[1].fields["iv"])
 }
 
 func TestEncryptDecryptWithHexaKeys(t *testing.T) {
@@ -563,3 +711,79 @@ func TestEncryptDecryptWithScaleFactor(t *testing.T) {
 		}
 	}
 }
+
+func buildNewBenchmarkPricer(b *testing.B) *DoubleClickPricer {
+	pricer, err := buildNewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	if err != nil {
+		b.Fatal("Error creating new Pricer : ", err)
+	}
+	return pricer
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pricer.Encrypt("seed", 1.354); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendEncrypt(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	seed := []byte("seed")
+	dst := make([]byte, 0, 38)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pricer.AppendEncrypt(dst[:0], seed, 1.354); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptBytes(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	encryptedPrice := []byte("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pricer.DecryptBytes(encryptedPrice); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptBytesParallel(b *testing.B) {
+	pricer := buildNewBenchmarkPricer(b)
+	encryptedPrice := []byte("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := pricer.DecryptBytes(encryptedPrice); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
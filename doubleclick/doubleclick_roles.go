@@ -0,0 +1,161 @@
+package doubleclick
+
+import (
+	"context"
+	"time"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// DecryptOnlyPricer is a DoubleClickPricer restricted to decryption, for
+// bidders which must not be able to mint valid encrypted prices.
+// It has no encryption method, and the pricer it wraps can't be reached,
+// so that the restriction is enforced at compile time.
+// A DecryptOnlyPricer is safe for concurrent use.
+type DecryptOnlyPricer struct {
+	pricer *DoubleClickPricer
+}
+
+var _ pricers.Decrypter = (*DecryptOnlyPricer)(nil)
+
+// NewDecryptOnlyPricer returns a DecryptOnlyPricer, taking the parameters of NewDoubleClickPricer.
+func NewDecryptOnlyPricer(
+	encryptionKey string,
+	integrityKey string,
+	isBase64Keys bool,
+	keyDecodingMode helpers.KeyDecodingMode,
+	scaleFactor float64,
+	isDebugMode bool,
+	options ...Option) (*DecryptOnlyPricer, error) {
+	pricer, err := NewDoubleClickPricer(encryptionKey, integrityKey, isBase64Keys, keyDecodingMode, scaleFactor, isDebugMode, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptOnlyPricer{pricer: pricer}, nil
+}
+
+// NewDecryptOnlyPricerWithKeys returns a DecryptOnlyPricer, taking the parameters of NewDoubleClickPricerWithKeys.
+func NewDecryptOnlyPricerWithKeys(
+	encryptionKey *helpers.Key,
+	integrityKey *helpers.Key,
+	scaleFactor float64,
+	isDebugMode bool,
+	options ...Option) (*DecryptOnlyPricer, error) {
+	pricer, err := NewDoubleClickPricerWithKeys(encryptionKey, integrityKey, scaleFactor, isDebugMode, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptOnlyPricer{pricer: pricer}, nil
+}
+
+// Decrypt decrypts an encrypted price, see DoubleClickPricer.Decrypt.
+func (p *DecryptOnlyPricer) Decrypt(encryptedPrice string) (float64, error) {
+	return p.pricer.Decrypt(encryptedPrice)
+}
+
+// DecryptWithTime decrypts an encrypted price along with its IV time, see DoubleClickPricer.DecryptWithTime.
+func (p *DecryptOnlyPricer) DecryptWithTime(encryptedPrice string) (float64, time.Time, error) {
+	return p.pricer.DecryptWithTime(encryptedPrice)
+}
+
+// DecryptBytes decrypts an encrypted price, see DoubleClickPricer.DecryptBytes.
+func (p *DecryptOnlyPricer) DecryptBytes(encryptedPrice []byte) (float64, error) {
+	return p.pricer.DecryptBytes(encryptedPrice)
+}
+
+// DecryptAmount decrypts an encrypted price in currency, see DoubleClickPricer.DecryptAmount.
+func (p *DecryptOnlyPricer) DecryptAmount(encryptedPrice string, currency string) (Amount, error) {
+	return p.pricer.DecryptAmount(encryptedPrice, currency)
+}
+
+// DecryptBatch decrypts encrypted prices concurrently, see DoubleClickPricer.DecryptBatch.
+func (p *DecryptOnlyPricer) DecryptBatch(ctx context.Context, encryptedPrices []string, workers int) []Result {
+	return p.pricer.DecryptBatch(ctx, encryptedPrices, workers)
+}
+
+// TokenCodec : Returns the name of the codec an encrypted price is decoded with, see DoubleClickPricer.TokenCodec.
+func (p *DecryptOnlyPricer) TokenCodec(encryptedPrice string) string {
+	return p.pricer.TokenCodec(encryptedPrice)
+}
+
+// Exchange : Returns the exchange name set with WithExchange, see DoubleClickPricer.Exchange.
+func (p *DecryptOnlyPricer) Exchange() string {
+	return p.pricer.Exchange()
+}
+
+// Zero retires the pricer, see DoubleClickPricer.Zero.
+func (p *DecryptOnlyPricer) Zero() {
+	p.pricer.Zero()
+}
+
+// EncryptOnlyPricer is a DoubleClickPricer restricted to encryption, for
+// exchanges and their simulators which must not decrypt prices.
+// It has no decryption method, and the pricer it wraps can't be reached,
+// so that the restriction is enforced at compile time.
+// An EncryptOnlyPricer is safe for concurrent use.
+type EncryptOnlyPricer struct {
+	pricer *DoubleClickPricer
+}
+
+var _ pricers.Encrypter = (*EncryptOnlyPricer)(nil)
+
+// NewEncryptOnlyPricer returns an EncryptOnlyPricer, taking the parameters of NewDoubleClickPricer.
+func NewEncryptOnlyPricer(
+	encryptionKey string,
+	integrityKey string,
+	isBase64Keys bool,
+	keyDecodingMode helpers.KeyDecodingMode,
+	scaleFactor float64,
+	isDebugMode bool,
+	options ...Option) (*EncryptOnlyPricer, error) {
+	pricer, err := NewDoubleClickPricer(encryptionKey, integrityKey, isBase64Keys, keyDecodingMode, scaleFactor, isDebugMode, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptOnlyPricer{pricer: pricer}, nil
+}
+
+// NewEncryptOnlyPricerWithKeys returns an EncryptOnlyPricer, taking the parameters of NewDoubleClickPricerWithKeys.
+func NewEncryptOnlyPricerWithKeys(
+	encryptionKey *helpers.Key,
+	integrityKey *helpers.Key,
+	scaleFactor float64,
+	isDebugMode bool,
+	options ...Option) (*EncryptOnlyPricer, error) {
+	pricer, err := NewDoubleClickPricerWithKeys(encryptionKey, integrityKey, scaleFactor, isDebugMode, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptOnlyPricer{pricer: pricer}, nil
+}
+
+// Encrypt encrypts a clear price and a given seed, see DoubleClickPricer.Encrypt.
+func (p *EncryptOnlyPricer) Encrypt(seed string, price float64) (string, error) {
+	return p.pricer.Encrypt(seed, price)
+}
+
+// AppendEncrypt encrypts a clear price and appends it to dst, see DoubleClickPricer.AppendEncrypt.
+func (p *EncryptOnlyPricer) AppendEncrypt(dst []byte, seed []byte, price float64) ([]byte, error) {
+	return p.pricer.AppendEncrypt(dst, seed, price)
+}
+
+// EncryptNow encrypts a clear price with a timestamp IV, see DoubleClickPricer.EncryptNow.
+func (p *EncryptOnlyPricer) EncryptNow(price float64) (string, error) {
+	return p.pricer.EncryptNow(price)
+}
+
+// AppendEncryptNow encrypts a clear price with a timestamp IV and appends it to dst, see DoubleClickPricer.AppendEncryptNow.
+func (p *EncryptOnlyPricer) AppendEncryptNow(dst []byte, price float64) ([]byte, error) {
+	return p.pricer.AppendEncryptNow(dst, price)
+}
+
+// EncodedSize : Returns the size of encrypted prices, see DoubleClickPricer.EncodedSize.
+func (p *EncryptOnlyPricer) EncodedSize() int {
+	return p.pricer.EncodedSize()
+}
+
+// Zero retires the pricer, see DoubleClickPricer.Zero.
+func (p *EncryptOnlyPricer) Zero() {
+	p.pricer.Zero()
+}
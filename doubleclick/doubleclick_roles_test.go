@@ -0,0 +1,74 @@
+package doubleclick
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func TestDecryptOnlyPricer(t *testing.T) {
+	// Setup:
+	pricer, err := NewDecryptOnlyPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+
+	// Execute:
+	result, err := pricer.Decrypt("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA")
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result)
+	var decrypter interface{} = pricer
+	_, canEncrypt := decrypter.(pricers.Encrypter)
+	assert.False(t, canEncrypt)
+}
+
+func TestEncryptOnlyPricer(t *testing.T) {
+	// Setup:
+	pricer, err := NewEncryptOnlyPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+
+	// Execute:
+	result, err := pricer.Encrypt("", 1.354)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", result)
+	var encrypter interface{} = pricer
+	_, canDecrypt := encrypter.(pricers.Decrypter)
+	assert.False(t, canDecrypt)
+}
+
+func TestRoleRestrictedPricersWithKeys(t *testing.T) {
+	// Setup:
+	newKeys := func() (*helpers.Key, *helpers.Key) {
+		encryptionKey, err := helpers.ParseKey("652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135", false, helpers.Hexa)
+		assert.Nil(t, err)
+		integrityKey, err := helpers.ParseKey("bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5", false, helpers.Hexa)
+		assert.Nil(t, err)
+		return encryptionKey, integrityKey
+	}
+	encryptionKey, integrityKey := newKeys()
+	encrypter, err := NewEncryptOnlyPricerWithKeys(encryptionKey, integrityKey, 1000000, false)
+	assert.Nil(t, err)
+	encryptionKey, integrityKey = newKeys()
+	decrypter, err := NewDecryptOnlyPricerWithKeys(encryptionKey, integrityKey, 1000000, false)
+	assert.Nil(t, err)
+
+	// Execute:
+	encrypted, err := encrypter.EncryptNow(2.5)
+	assert.Nil(t, err)
+	result, err := decrypter.Decrypt(encrypted)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 2.5, result)
+}
@@ -0,0 +1,231 @@
+package doubleclick
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/metrics"
+)
+
+// ErrUnknownSeat is returned by a Router when a seat has no pricer.
+var ErrUnknownSeat = errors.New("Seat has no pricer")
+
+// FallbackPolicy : Describing what a Router does when a seat has no pricer,
+// or when its pricer doesn't validate the signature of an encrypted price.
+type FallbackPolicy int
+
+const (
+	// FallbackFail fails, the encrypted price being tried with the seat pricer only. It is the default.
+	FallbackFail FallbackPolicy = iota
+	// FallbackTryAll tries the pricers of every other seat, in seat ID order,
+	// until one validates the signature, whatever the others fail with.
+	FallbackTryAll
+)
+
+// SeatStats counts the decryptions a Router was asked for a seat.
+type SeatStats struct {
+	// Decrypted is the number of encrypted prices decrypted by the seat pricer.
+	Decrypted int64
+	// Fallbacks is the number of encrypted prices decrypted by the pricer of another seat.
+	Fallbacks int64
+	// Failed is the number of encrypted prices that could not be decrypted.
+	Failed int64
+}
+
+// seatCounters are the SeatStats counters of a seat.
+type seatCounters struct {
+	decrypted atomic.Int64
+	fallbacks atomic.Int64
+	failed    atomic.Int64
+}
+
+// Router decrypts encrypted prices with the pricer of the seat, or buyer
+// account, they were issued for, exchanges issuing distinct keys per seat.
+// A Router is safe for concurrent use.
+type Router struct {
+	pricers  map[string]pricers.Decrypter
+	seats    []string
+	policy   FallbackPolicy
+	observer metrics.Observer
+	// counters are keyed by seat ID, seats without a pricer sharing the
+	// empty seat ID, so that callers can't grow the map.
+	counters map[string]*seatCounters
+}
+
+// RouterOption is a Router option.
+type RouterOption func(*Router)
+
+// WithFallbackPolicy sets the fallback policy, FallbackFail by default.
+func WithFallbackPolicy(policy FallbackPolicy) RouterOption {
+	return func(r *Router) {
+		r.policy = policy
+	}
+}
+
+// WithRouterObserver sets the observer notified of every routed decryption,
+// events carrying the seat ID, or an empty one for seats without a pricer.
+// Their exchange is the one of the pricer that decrypted the price, or
+// failed to, as told by its Exchange method, or its seat ID if it has none.
+func WithRouterObserver(observer metrics.Observer) RouterOption {
+	return func(r *Router) {
+		r.observer = observer
+	}
+}
+
+// NewRouter returns a Router decrypting encrypted prices with the pricers
+// keyed by seat ID, e.g. DoubleClickPricer or DecryptOnlyPricer.
+func NewRouter(seatPricers map[string]pricers.Decrypter, options ...RouterOption) (*Router, error) {
+	r := &Router{
+		pricers:  make(map[string]pricers.Decrypter, len(seatPricers)),
+		counters: map[string]*seatCounters{"": {}},
+	}
+	for seat, pricer := range seatPricers {
+		if seat == "" {
+			return nil, fmt.Errorf("doubleclick: seat ID is empty")
+		}
+		if pricer == nil {
+			return nil, fmt.Errorf("doubleclick: seat %q has no pricer", seat)
+		}
+		r.pricers[seat] = pricer
+		r.seats = append(r.seats, seat)
+		r.counters[seat] = &seatCounters{}
+	}
+	sort.Strings(r.seats)
+	for _, option := range options {
+		option(r)
+	}
+	return r, nil
+}
+
+// Seats : Returns the sorted IDs of the seats having a pricer.
+func (r *Router) Seats() []string {
+	return append([]string(nil), r.seats...)
+}
+
+// Decrypt decrypts an encrypted price issued for a seat.
+// If the seat has no pricer, ErrUnknownSeat is returned unless the fallback
+// policy is FallbackTryAll. Errors are otherwise the ones of the seat pricer.
+func (r *Router) Decrypt(seatID string, encryptedPrice string) (float64, error) {
+	start := time.Now()
+	price, matched, err := r.decrypt(seatID, encryptedPrice)
+	fallback := err == nil && matched != seatID
+
+	counted := seatID
+	counters, ok := r.counters[seatID]
+	if !ok {
+		counted, counters = "", r.counters[""]
+	}
+	switch {
+	case err != nil:
+		counters.failed.Add(1)
+	case fallback:
+		counters.fallbacks.Add(1)
+	default:
+		counters.decrypted.Add(1)
+	}
+	if r.observer != nil {
+		outcome := OutcomeOf(err)
+		if errors.Is(err, ErrUnknownSeat) {
+			outcome = metrics.UnknownSeat
+		}
+		r.observer.Observe(metrics.Event{
+			Protocol:  Protocol,
+			Exchange:  r.exchange(matched),
+			Operation: metrics.Decrypt,
+			Outcome:   outcome,
+			Latency:   time.Since(start),
+			Seat:      counted,
+		})
+	}
+	return price, err
+}
+
+// decrypt : Returns the decrypted price, along with the seat whose pricer
+// decrypted it, or failed to, empty if the seat has no pricer.
+// With FallbackTryAll, the error of the seat pricer is returned once every
+// other pricer failed.
+func (r *Router) decrypt(seatID string, encryptedPrice string) (float64, string, error) {
+	var err error
+	pricer, ok := r.pricers[seatID]
+	if ok {
+		var price float64
+		if price, err = pricer.Decrypt(encryptedPrice); !isForeign(err) {
+			return price, seatID, err
+		}
+	} else {
+		err = fmt.Errorf("%w: %q", ErrUnknownSeat, seatID)
+	}
+	if r.policy != FallbackTryAll {
+		return 0, r.known(seatID), err
+	}
+
+	for _, seat := range r.seats {
+		if seat == seatID {
+			continue
+		}
+		// Pricers of other seats may expect other codecs or sizes, their
+		// errors telling only that the price wasn't issued for them.
+		price, fallbackErr := r.pricers[seat].Decrypt(encryptedPrice)
+		if fallbackErr == nil || KindOf(fallbackErr) == KindRange {
+			return price, seat, fallbackErr
+		}
+	}
+	return 0, r.known(seatID), err
+}
+
+// known : Returns seatID if it has a pricer, empty otherwise.
+func (r *Router) known(seatID string) string {
+	if _, ok := r.pricers[seatID]; ok {
+		return seatID
+	}
+	return ""
+}
+
+// exchange : Returns the exchange of the pricer of a seat, its seat ID if
+// the pricer has none, empty for seats without a pricer.
+func (r *Router) exchange(seatID string) string {
+	if named, ok := r.pricers[seatID].(interface{ Exchange() string }); ok && named.Exchange() != "" {
+		return named.Exchange()
+	}
+	return seatID
+}
+
+// isForeign tells whether a decryption error may come from the encrypted
+// price having been issued for other keys.
+func isForeign(err error) bool {
+	kind := KindOf(err)
+	return kind == KindSignature || kind == KindKeyMismatch
+}
+
+// Seat : Returns a Decrypter decrypting encrypted prices issued for seatID
+// with the router, e.g. to be given to openrtb.DecryptPrice.
+func (r *Router) Seat(seatID string) pricers.Decrypter {
+	return seatDecrypter{router: r, seatID: seatID}
+}
+
+type seatDecrypter struct {
+	router *Router
+	seatID string
+}
+
+func (d seatDecrypter) Decrypt(encryptedPrice string) (float64, error) {
+	return d.router.Decrypt(d.seatID, encryptedPrice)
+}
+
+// Stats : Returns the decryption counts of every seat, seats without
+// a pricer being counted together under the empty seat ID.
+func (r *Router) Stats() map[string]SeatStats {
+	stats := make(map[string]SeatStats, len(r.counters))
+	for seat, counters := range r.counters {
+		stats[seat] = SeatStats{
+			Decrypted: counters.decrypted.Load(),
+			Fallbacks: counters.fallbacks.Load(),
+			Failed:    counters.failed.Load(),
+		}
+	}
+	return stats
+}
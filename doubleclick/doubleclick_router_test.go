@@ -0,0 +1,228 @@
+package doubleclick
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/metrics"
+)
+
+const routedPrice = "1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA"
+
+// buildRouter returns a Router whose "seat-a" pricer issued routedPrice,
+// the "seat-b" pricer having other keys.
+func buildRouter(t *testing.T, options ...RouterOption) *Router {
+	t.Helper()
+	seatA, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+	seatB, err := NewDecryptOnlyPricer(
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+	router, err := NewRouter(map[string]pricers.Decrypter{"seat-a": seatA, "seat-b": seatB}, options...)
+	assert.Nil(t, err)
+	return router
+}
+
+func TestRouterDecrypt(t *testing.T) {
+	// Setup:
+	router := buildRouter(t)
+
+	// Execute:
+	result, err := router.Decrypt("seat-a", routedPrice)
+	_, otherSeatErr := router.Decrypt("seat-b", routedPrice)
+	_, unknownSeatErr := router.Decrypt("seat-c", routedPrice)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result)
+	assert.True(t, isForeign(otherSeatErr))
+	assert.ErrorIs(t, unknownSeatErr, ErrUnknownSeat)
+	assert.EqualError(t, unknownSeatErr, `Seat has no pricer: "seat-c"`)
+	assert.Equal(t, []string{"seat-a", "seat-b"}, router.Seats())
+	assert.Equal(t, map[string]SeatStats{
+		"":       {Failed: 1},
+		"seat-a": {Decrypted: 1},
+		"seat-b": {Failed: 1},
+	}, router.Stats())
+}
+
+func TestRouterFallbackTryAll(t *testing.T) {
+	// Setup:
+	router := buildRouter(t, WithFallbackPolicy(FallbackTryAll))
+
+	// Execute:
+	otherSeatResult, otherSeatErr := router.Decrypt("seat-b", routedPrice)
+	unknownSeatResult, unknownSeatErr := router.Decrypt("seat-c", routedPrice)
+	_, wrongSizeErr := router.Decrypt("seat-b", "too short")
+	_, wrongSignatureErr := router.Decrypt("seat-b", routedPrice[:30]+"AAAA"+routedPrice[34:])
+
+	// Verify:
+	assert.Nil(t, otherSeatErr)
+	assert.Equal(t, 1.354, otherSeatResult)
+	assert.Nil(t, unknownSeatErr)
+	assert.Equal(t, 1.354, unknownSeatResult)
+	assert.Equal(t, KindSize, KindOf(wrongSizeErr))
+	assert.True(t, isForeign(wrongSignatureErr))
+	assert.Equal(t, map[string]SeatStats{
+		"":       {Fallbacks: 1},
+		"seat-a": {},
+		"seat-b": {Fallbacks: 1, Failed: 2},
+	}, router.Stats())
+}
+
+func TestRouterFallbackOnKeyMismatch(t *testing.T) {
+	// Setup:
+	encryptionKey, err := helpers.ParseKey("652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135", false, helpers.Hexa)
+	assert.Nil(t, err)
+	integrityKey, err := helpers.ParseKey("bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5", false, helpers.Hexa)
+	assert.Nil(t, err)
+	seatA, err := NewDoubleClickPricerWithKeys(encryptionKey, integrityKey, 1000000, false)
+	assert.Nil(t, err)
+	seatB, err := NewDoubleClickPricer(
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		false, helpers.Hexa, 1000000, false, WithForeignKeys(encryptionKey, integrityKey))
+	assert.Nil(t, err)
+	router, err := NewRouter(map[string]pricers.Decrypter{"seat-a": seatA, "seat-b": seatB}, WithFallbackPolicy(FallbackTryAll))
+	assert.Nil(t, err)
+	_, mismatchErr := seatB.Decrypt(routedPrice)
+
+	// Execute:
+	result, err := router.Decrypt("seat-b", routedPrice)
+
+	// Verify:
+	assert.Equal(t, KindKeyMismatch, KindOf(mismatchErr))
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result)
+}
+
+func TestRouterFallbackTryAllSkipsOtherFormats(t *testing.T) {
+	// Setup:
+	seatA, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+	// Tried first, seat IDs being sorted, hex encrypted prices being longer.
+	hexSeat, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false, WithCodec(Hex))
+	assert.Nil(t, err)
+	otherKeys, err := NewDoubleClickPricer(
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+	router, err := NewRouter(map[string]pricers.Decrypter{"seat-0": hexSeat, "seat-a": seatA, "seat-b": otherKeys},
+		WithFallbackPolicy(FallbackTryAll))
+	assert.Nil(t, err)
+	_, hexErr := hexSeat.Decrypt(routedPrice)
+
+	// Execute:
+	result, err := router.Decrypt("seat-b", routedPrice)
+	_, failedErr := router.Decrypt("seat-b", routedPrice[:30]+"AAAA"+routedPrice[34:])
+
+	// Verify:
+	assert.Equal(t, KindSize, KindOf(hexErr))
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result)
+	assert.Equal(t, KindSignature, KindOf(failedErr))
+}
+
+func TestRouterObserver(t *testing.T) {
+	// Setup:
+	var mu sync.Mutex
+	var events []metrics.Event
+	observer := metrics.ObserverFunc(func(event metrics.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	router := buildRouter(t, WithRouterObserver(observer))
+
+	// Execute:
+	_, _ = router.Decrypt("seat-a", routedPrice)
+	_, _ = router.Decrypt("seat-c", routedPrice)
+
+	// Verify:
+	assert.Len(t, events, 2)
+	assert.Equal(t, "seat-a", events[0].Seat)
+	assert.Equal(t, metrics.OK, events[0].Outcome)
+	assert.Equal(t, metrics.Decrypt, events[0].Operation)
+	assert.Equal(t, Protocol, events[0].Protocol)
+	assert.Equal(t, "", events[1].Seat)
+	assert.Equal(t, metrics.UnknownSeat, events[1].Outcome)
+	assert.Equal(t, "seat-a", events[0].Exchange)
+	assert.Equal(t, "", events[1].Exchange)
+}
+
+func TestRouterObserverExchange(t *testing.T) {
+	// Setup:
+	var events []metrics.Event
+	observer := metrics.ObserverFunc(func(event metrics.Event) {
+		events = append(events, event)
+	})
+	seatA, err := NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, helpers.Hexa, 1000000, false, WithExchange("adx-a"))
+	assert.Nil(t, err)
+	seatB, err := NewDecryptOnlyPricer(
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		false, helpers.Hexa, 1000000, false, WithExchange("adx-b"))
+	assert.Nil(t, err)
+	router, err := NewRouter(map[string]pricers.Decrypter{"seat-a": seatA, "seat-b": seatB},
+		WithFallbackPolicy(FallbackTryAll), WithRouterObserver(observer))
+	assert.Nil(t, err)
+
+	// Execute:
+	_, _ = router.Decrypt("seat-b", routedPrice)
+	_, _ = router.Decrypt("seat-b", "too short")
+
+	// Verify:
+	assert.Len(t, events, 2)
+	assert.Equal(t, "seat-b", events[0].Seat)
+	assert.Equal(t, "adx-a", events[0].Exchange)
+	assert.Equal(t, metrics.OK, events[0].Outcome)
+	assert.Equal(t, "adx-b", events[1].Exchange)
+	assert.Equal(t, metrics.WrongSize, events[1].Outcome)
+}
+
+func TestRouterSeat(t *testing.T) {
+	// Setup:
+	router := buildRouter(t)
+
+	// Execute:
+	result, err := router.Seat("seat-a").Decrypt(routedPrice)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, result)
+	assert.Equal(t, int64(1), router.Stats()["seat-a"].Decrypted)
+}
+
+func TestNewRouterErrors(t *testing.T) {
+	// Setup:
+	pricer := buildRouter(t).Seat("seat-a")
+
+	// Execute:
+	_, emptySeatErr := NewRouter(map[string]pricers.Decrypter{"": pricer})
+	_, nilPricerErr := NewRouter(map[string]pricers.Decrypter{"seat-a": nil})
+
+	// Verify:
+	assert.EqualError(t, emptySeatErr, "doubleclick: seat ID is empty")
+	assert.EqualError(t, nilPricerErr, `doubleclick: seat "seat-a" has no pricer`)
+	assert.False(t, errors.Is(nilPricerErr, ErrUnknownSeat))
+}
@@ -7,9 +7,10 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"hash"
 	"strings"
+
+	"github.com/benjaminch/pricers/logging"
 )
 
 // KeyDecodingMode : Describing how keys should be decoded.
@@ -50,8 +51,8 @@ func ParseKeyDecodingMode(input string) (KeyDecodingMode, error) {
 	return parsed, err
 }
 
-// CreateHmac : Returns Hash from input string.
-func CreateHmac(key string, isBase64 bool, mode KeyDecodingMode) (hash.Hash, error) {
+// DecodeKey : Returns key bytes from input string.
+func DecodeKey(key string, isBase64 bool, mode KeyDecodingMode) ([]byte, error) {
 	var err error
 	var b64DecodedKey []byte
 	var k []byte
@@ -74,27 +75,74 @@ func CreateHmac(key string, isBase64 bool, mode KeyDecodingMode) (hash.Hash, err
 		return nil, err
 	}
 
-	return hmac.New(sha1.New, k), nil
+	return k, nil
+}
+
+// EncodeKey : Returns the string representation of key bytes, reversing DecodeKey.
+// Base64 keys are web safe, unpadded.
+func EncodeKey(key []byte, isBase64 bool, mode KeyDecodingMode) string {
+	encoded := string(key)
+	if mode == Hexa {
+		encoded = hex.EncodeToString(key)
+	}
+	if isBase64 {
+		encoded = base64.RawURLEncoding.EncodeToString([]byte(encoded))
+	}
+	return encoded
+}
+
+// CreateHmac : Returns HMAC-SHA1 Hash from input string.
+func CreateHmac(key string, isBase64 bool, mode KeyDecodingMode) (hash.Hash, error) {
+	return CreateHmacWithHash(sha1.New, key, isBase64, mode)
+}
+
+// CreateHmacWithHash : Returns HMAC Hash using the newHash hash function from input string.
+func CreateHmacWithHash(newHash func() hash.Hash, key string, isBase64 bool, mode KeyDecodingMode) (hash.Hash, error) {
+	k, err := DecodeKey(key, isBase64, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return hmac.New(newHash, k), nil
 }
 
 // HmacSum : Returns Hmac sum bytes.
 func HmacSum(hmac hash.Hash, buf, buf2 []byte) []byte {
+	return AppendHmacSum(nil, hmac, buf, buf2)
+}
+
+// AppendHmacSum : Appends Hmac sum bytes to dst and returns the extended buffer.
+// It doesn't allocate when dst has enough capacity.
+func AppendHmacSum(dst []byte, hmac hash.Hash, buf, buf2 []byte) []byte {
 	hmac.Reset()
 	hmac.Write(buf)
 	if buf2 != nil {
 		hmac.Write(buf2)
 	}
-	return hmac.Sum(nil)
+	return hmac.Sum(dst)
 }
 
 // ApplyScaleFactor : Applies a scale factor to a given price.
 // Scaled price will be represented on 8 bytes.
+// If isDebugMode is true, a debug event is written as text to stderr.
 func ApplyScaleFactor(price float64, scaleFactor float64, isDebugMode bool) [8]byte {
+	var logger logging.Logger
+	if isDebugMode {
+		logger = logging.NewStderrLogger()
+	}
+	return ApplyScaleFactorWithLogger(price, scaleFactor, logger)
+}
+
+// ApplyScaleFactorWithLogger : Applies a scale factor to a given price, as
+// ApplyScaleFactor does. A debug event is emitted to logger, if not nil.
+func ApplyScaleFactorWithLogger(price float64, scaleFactor float64, logger logging.Logger) [8]byte {
 	scaledPrice := [8]byte{}
 	binary.BigEndian.PutUint64(scaledPrice[:], uint64(price*scaleFactor))
 
-	if isDebugMode {
-		fmt.Printf("Micro price bytes: %v", scaledPrice)
+	if logger != nil {
+		logger.Debug("helpers: scale factor applied",
+			logging.F("scale_factor", scaleFactor),
+			logging.F("scaled_price", hex.EncodeToString(scaledPrice[:])))
 	}
 
 	return scaledPrice
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func FuzzCreateHmac(f *testing.F) {
+	f.Add("ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU", true, string(Utf8))
+	f.Add("vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U==", true, string(Hexa))
+	f.Add("652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135", false, string(Hexa))
+	f.Add("6356770B3C111C07F778AFD69F16643E9110090FD4C479D91181EED2523788F1", false, string(Utf8))
+	f.Add("", false, "")
+
+	f.Fuzz(func(t *testing.T, key string, isBase64 bool, mode string) {
+		hmac, err := CreateHmac(key, isBase64, KeyDecodingMode(mode))
+		if err != nil {
+			if hmac != nil {
+				t.Fatalf("CreateHmac(%q, %t, %q) returned both a hash and an error", key, isBase64, mode)
+			}
+			return
+		}
+		if len(HmacSum(hmac, []byte("iv"), nil)) != hmac.Size() {
+			t.Fatalf("CreateHmac(%q, %t, %q) returned an unusable hash", key, isBase64, mode)
+		}
+	})
+}
+
+func FuzzParseKeyDecodingMode(f *testing.F) {
+	f.Add("utf-8")
+	f.Add("hexa")
+	f.Add("")
+	f.Add("UTF-8")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		parsed, err := ParseKeyDecodingMode(input)
+		if err != nil {
+			if parsed != "" {
+				t.Fatalf("ParseKeyDecodingMode(%q) returned both %q and an error", input, parsed)
+			}
+			return
+		}
+		if parsed.String() != input {
+			t.Fatalf("ParseKeyDecodingMode(%q) = %q", input, parsed)
+		}
+	})
+}
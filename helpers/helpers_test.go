@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/logging"
+)
+
+type recordingLogger struct {
+	events []string
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...logging.Field) {
+	l.events = append(l.events, msg)
+}
+
+func TestApplyScaleFactor(t *testing.T) {
+	// Setup:
+	logger := &recordingLogger{}
+	expected := [8]byte{0, 0, 0, 0, 0, 0x14, 0xa9, 0x10}
+
+	// Execute:
+	scaled := ApplyScaleFactor(1.354, 1000000, false)
+	logged := ApplyScaleFactorWithLogger(1.354, 1000000, logger)
+
+	// Verify:
+	assert.Equal(t, expected, scaled)
+	assert.Equal(t, expected, logged)
+	assert.Equal(t, []string{"helpers: scale factor applied"}, logger.events)
+}
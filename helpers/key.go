@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+)
+
+// fingerprintSize is the number of bytes of a key fingerprint.
+const fingerprintSize = 3
+
+// Key holds decoded key material.
+// Its string, fmt and slog representations are redacted, only telling the
+// key length and fingerprint, so that a key can't end up in logs by mistake.
+type Key struct {
+	b []byte
+}
+
+// NewKey returns a Key holding a copy of b.
+func NewKey(b []byte) *Key {
+	return &Key{b: append([]byte(nil), b...)}
+}
+
+// ParseKey returns the Key decoded from its string representation, as DecodeKey does.
+func ParseKey(key string, isBase64 bool, mode KeyDecodingMode) (*Key, error) {
+	b, err := DecodeKey(key, isBase64, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{b: b}, nil
+}
+
+// Bytes : Returns the key material. It must not be modified nor logged.
+func (k Key) Bytes() []byte {
+	return k.b
+}
+
+// Len : Returns the key length in bytes.
+func (k Key) Len() int {
+	return len(k.b)
+}
+
+// Fingerprint returns the key check value of the key: the first 3 bytes,
+// as hexa, of its HMAC-SHA256 over 32 zero bytes. It tells keys apart in
+// logs and configuration files without revealing them.
+// The fingerprint of an empty or zeroed key is empty.
+func (k Key) Fingerprint() string {
+	if len(k.b) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.b)
+	mac.Write(make([]byte, sha256.Size))
+	return hex.EncodeToString(mac.Sum(nil)[:fingerprintSize])
+}
+
+// Equal : Returns true if both keys hold the same material, in constant time.
+func (k Key) Equal(other Key) bool {
+	return subtle.ConstantTimeCompare(k.b, other.b) == 1
+}
+
+// Zero overwrites the key material with zeros, so that it doesn't linger
+// in memory once the key is retired. The key is empty afterwards.
+func (k *Key) Zero() {
+	clear(k.b)
+	k.b = nil
+}
+
+// String : Returns the Key redacted string representation.
+func (k Key) String() string {
+	if len(k.b) == 0 {
+		return "[REDACTED 0 bytes]"
+	}
+	return fmt.Sprintf("[REDACTED %d bytes, fingerprint %s]", len(k.b), k.Fingerprint())
+}
+
+// Format : Writes the Key redacted string representation, whatever the verb,
+// so that e.g. %x or %#v don't print the key material.
+func (k Key) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// LogValue : Returns the Key redacted slog representation.
+func (k Key) LogValue() slog.Value {
+	return slog.StringValue(k.String())
+}
@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyIsNeverPrinted(t *testing.T) {
+	// Setup:
+	key := NewKey([]byte("secret"))
+	const redacted = "[REDACTED 6 bytes, fingerprint 92831b]"
+
+	// Verify:
+	assert.Equal(t, redacted, key.String())
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d"} {
+		assert.Equal(t, redacted, fmt.Sprintf(format, key), format)
+		assert.Equal(t, redacted, fmt.Sprintf(format, *key), format)
+	}
+	assert.Equal(t, "{Key:"+redacted+"}", fmt.Sprintf("%+v", struct{ Key *Key }{key}))
+}
+
+func TestKeyLogValue(t *testing.T) {
+	// Setup:
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	// Execute:
+	logger.Info("key loaded", slog.Any("key", NewKey([]byte("secret"))))
+
+	// Verify:
+	assert.Contains(t, buf.String(), `key="[REDACTED 6 bytes, fingerprint 92831b]"`)
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestParseKey(t *testing.T) {
+	// Setup:
+	hexKey := "652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135"
+	base64Key := "ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU"
+
+	// Execute:
+	fromHex, err := ParseKey(hexKey, false, Hexa)
+	assert.Nil(t, err)
+	fromBase64, err := ParseKey(base64Key, true, Utf8)
+	assert.Nil(t, err)
+	_, err = ParseKey("not hexa", false, Hexa)
+
+	// Verify:
+	assert.NotNil(t, err)
+	assert.Equal(t, 32, fromHex.Len())
+	assert.True(t, fromHex.Equal(*fromBase64))
+	assert.Equal(t, "06ae61", fromHex.Fingerprint())
+	assert.Equal(t, fromHex.Fingerprint(), fromBase64.Fingerprint())
+}
+
+func TestKeyZero(t *testing.T) {
+	// Setup:
+	material := []byte("secret")
+	key := &Key{b: material}
+
+	// Execute:
+	key.Zero()
+
+	// Verify:
+	assert.Equal(t, make([]byte, 6), material)
+	assert.Equal(t, 0, key.Len())
+	assert.Equal(t, "", key.Fingerprint())
+	assert.Equal(t, "[REDACTED 0 bytes]", key.String())
+}
+
+func TestNewKeyCopies(t *testing.T) {
+	// Setup:
+	material := []byte("secret")
+	key := NewKey(material)
+
+	// Execute:
+	material[0] = 'S'
+
+	// Verify:
+	assert.Equal(t, []byte("secret"), key.Bytes())
+}
+
+func TestEncodeKey(t *testing.T) {
+	// Setup:
+	key, err := DecodeKey("652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135", false, Hexa)
+	assert.Nil(t, err)
+
+	for _, isBase64 := range []bool{false, true} {
+		for _, mode := range []KeyDecodingMode{Utf8, Hexa} {
+			// Execute:
+			encoded := EncodeKey(key, isBase64, mode)
+			decoded, err := DecodeKey(encoded, isBase64, mode)
+
+			// Verify:
+			assert.Nil(t, err)
+			assert.Equal(t, key, decoded, "base64 %t, mode %s", isBase64, mode)
+		}
+	}
+	assert.Equal(t, "ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU", EncodeKey(key, true, Utf8))
+}
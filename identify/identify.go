@@ -0,0 +1,157 @@
+// Package identify tells which protocol, and exchange, issued an encrypted
+// price received without a reliable exchange marker.
+package identify
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/doubleclick"
+)
+
+// Candidate is a protocol, and possibly an exchange, a token may have been issued with.
+type Candidate struct {
+	// Protocol is the price encryption protocol, e.g. doubleclick.Protocol.
+	Protocol string
+	// Exchange is the ID of the exchange whose pricer validates the signature, if any.
+	Exchange string
+	// Codec is the name of the codec the token is encoded with. For verified
+	// candidates, it is the codec the pricer decoded the token with, empty if
+	// the pricer doesn't tell it.
+	Codec string
+	// Score ranks candidates, from 0 to 1, verified candidates scoring 1.
+	Score float64
+	// Verified tells whether the pricer of Exchange validates the signature.
+	Verified bool
+	// Reasons tell in plain words what the score is based on.
+	Reasons []string
+}
+
+// Analyzer : Returns the candidates of a protocol a token may have been
+// issued with, looking at its length, alphabet and structure.
+type Analyzer func(token string) []Candidate
+
+var (
+	analyzersMu sync.RWMutex
+	analyzers   = map[string]Analyzer{
+		doubleclick.Protocol: analyzeDoubleClick,
+	}
+)
+
+// Register makes a protocol known to identifiers.
+// It is meant to be called from an init function, to plug in-house protocols.
+func Register(protocol string, analyzer Analyzer) {
+	analyzersMu.Lock()
+	defer analyzersMu.Unlock()
+	analyzers[protocol] = analyzer
+}
+
+func analyzeDoubleClick(token string) []Candidate {
+	layouts := doubleclick.Analyze(token)
+	candidates := make([]Candidate, 0, len(layouts))
+	for _, layout := range layouts {
+		candidates = append(candidates, Candidate{
+			Protocol: doubleclick.Protocol,
+			Codec:    layout.Codec,
+			Score:    layout.Score,
+			Reasons:  layout.Reasons,
+		})
+	}
+	return candidates
+}
+
+// Exchange is a configured exchange tokens are trial-verified against.
+type Exchange struct {
+	// ID is the exchange ID.
+	ID string
+	// Protocol is the protocol of the exchange pricer.
+	Protocol string
+	// Decrypter is the exchange pricer.
+	Decrypter pricers.Decrypter
+}
+
+// codecReporter is a pricer telling the codec it decodes a token with,
+// as doubleclick.DoubleClickPricer does.
+type codecReporter interface {
+	TokenCodec(encryptedPrice string) string
+}
+
+// Identifier ranks the protocols and exchanges tokens may have been issued with.
+// An Identifier is safe for concurrent use.
+type Identifier struct {
+	exchanges []Exchange
+}
+
+// NewIdentifier returns an Identifier trial-verifying tokens against the
+// pricers of exchanges, if any. Pricer observers are notified of the
+// trial decryptions, which mostly fail.
+func NewIdentifier(exchanges ...Exchange) *Identifier {
+	sorted := append([]Exchange(nil), exchanges...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return &Identifier{exchanges: sorted}
+}
+
+// Identify : Returns the ranked candidates of a token, looking at its structure only.
+func Identify(token string) []Candidate {
+	return NewIdentifier().Identify(token)
+}
+
+// Identify : Returns the candidates a token may have been issued with, the
+// most likely first, or none if it is not an encrypted price of a known
+// protocol. Exchanges whose pricer validates the signature come first.
+func (id *Identifier) Identify(token string) []Candidate {
+	analyzersMu.RLock()
+	protocols := make(map[string][]Candidate, len(analyzers))
+	for protocol, analyzer := range analyzers {
+		if candidates := analyzer(token); len(candidates) > 0 {
+			protocols[protocol] = candidates
+		}
+	}
+	analyzersMu.RUnlock()
+
+	var verified, structural []Candidate
+	for _, exchange := range id.exchanges {
+		_, ok := protocols[exchange.Protocol]
+		if !ok || !validates(exchange.Decrypter, token) {
+			continue
+		}
+		var codec string
+		if reporter, ok := exchange.Decrypter.(codecReporter); ok {
+			codec = reporter.TokenCodec(token)
+		}
+		verified = append(verified, Candidate{
+			Protocol: exchange.Protocol,
+			Exchange: exchange.ID,
+			Codec:    codec,
+			Score:    1,
+			Verified: true,
+			Reasons:  []string{fmt.Sprintf("signature validates with the %q pricer", exchange.ID)},
+		})
+	}
+	for _, candidates := range protocols {
+		for _, candidate := range candidates {
+			if len(id.exchanges) > 0 && len(verified) == 0 {
+				candidate.Reasons = append(candidate.Reasons, "no configured pricer validates the signature")
+			}
+			structural = append(structural, candidate)
+		}
+	}
+	sort.SliceStable(structural, func(i, j int) bool {
+		if structural[i].Score != structural[j].Score {
+			return structural[i].Score > structural[j].Score
+		}
+		return structural[i].Protocol < structural[j].Protocol
+	})
+	return append(verified, structural...)
+}
+
+// validates tells whether a pricer validates the signature of a token,
+// out of range prices being authentic.
+func validates(decrypter pricers.Decrypter, token string) bool {
+	_, err := decrypter.Decrypt(token)
+	return err == nil || doubleclick.KindOf(err) == doubleclick.KindRange
+}
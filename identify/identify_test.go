@@ -0,0 +1,112 @@
+package identify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// buildIdentifier returns an Identifier of the adx and openx exchanges,
+// along with the openx pricer.
+func buildIdentifier(t *testing.T) (*Identifier, *doubleclick.DoubleClickPricer) {
+	t.Helper()
+	adx, err := doubleclick.NewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, helpers.Utf8, 1000000, false)
+	assert.Nil(t, err)
+	openx, err := doubleclick.NewDoubleClickPricer(
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		false, helpers.Hexa, 1000000, false)
+	assert.Nil(t, err)
+	return NewIdentifier(
+		Exchange{ID: "openx", Protocol: doubleclick.Protocol, Decrypter: openx},
+		Exchange{ID: "adx", Protocol: doubleclick.Protocol, Decrypter: adx},
+	), openx
+}
+
+func TestIdentify(t *testing.T) {
+	// Execute:
+	candidates := Identify("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	malformed := Identify("too short")
+
+	// Verify:
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, doubleclick.Protocol, candidates[0].Protocol)
+	assert.Equal(t, "base64url", candidates[0].Codec)
+	assert.Empty(t, candidates[0].Exchange)
+	assert.False(t, candidates[0].Verified)
+	assert.Empty(t, malformed)
+}
+
+func TestIdentifierVerifiesSignatures(t *testing.T) {
+	// Setup:
+	identifier, openxPricer := buildIdentifier(t)
+	openxToken, err := openxPricer.Encrypt("", 1.354)
+	assert.Nil(t, err)
+
+	// Execute:
+	adx := identifier.Identify("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	openx := identifier.Identify(openxToken)
+	unknown := identifier.Identify("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+
+	// Verify:
+	assert.Len(t, adx, 2)
+	assert.Equal(t, Candidate{
+		Protocol: doubleclick.Protocol,
+		Exchange: "adx",
+		Codec:    "base64url",
+		Score:    1,
+		Verified: true,
+		Reasons:  []string{`signature validates with the "adx" pricer`},
+	}, adx[0])
+	assert.False(t, adx[1].Verified)
+	assert.Equal(t, "openx", openx[0].Exchange)
+	assert.Len(t, unknown, 1)
+	assert.False(t, unknown[0].Verified)
+	assert.Contains(t, unknown[0].Reasons, "no configured pricer validates the signature")
+}
+
+func TestIdentifierReportsTheCodecThatValidates(t *testing.T) {
+	// Setup:
+	pricer, err := doubleclick.NewDoubleClickPricer(
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		false, helpers.Hexa, 1000000, false, doubleclick.WithCodec(doubleclick.Base64))
+	assert.Nil(t, err)
+	identifier := NewIdentifier(Exchange{ID: "openx", Protocol: doubleclick.Protocol, Decrypter: pricer})
+	// A padded token without '+' nor '/' reads as base64url-padded first.
+	token, err := pricer.Encrypt("a", 1.354)
+	assert.Nil(t, err)
+	assert.False(t, strings.ContainsAny(token, "+/"))
+
+	// Execute:
+	candidates := identifier.Identify(token)
+
+	// Verify:
+	assert.True(t, candidates[0].Verified)
+	assert.Equal(t, "base64", candidates[0].Codec)
+	assert.Equal(t, "base64url-padded", candidates[1].Codec)
+}
+
+func TestRegister(t *testing.T) {
+	// Setup:
+	Register("test", func(token string) []Candidate {
+		if !strings.HasPrefix(token, "test:") {
+			return nil
+		}
+		return []Candidate{{Codec: "text", Score: 0.5}}
+	})
+
+	// Execute:
+	candidates := Identify("test:1.354")
+
+	// Verify:
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, "text", candidates[0].Codec)
+}
@@ -0,0 +1,26 @@
+module github.com/benjaminch/pricers/instrumentation/otelpricers
+
+go 1.21
+
+require (
+	github.com/benjaminch/pricers v0.0.0-20261019022350-7a6f9fc5083a
+	github.com/stretchr/testify v1.10.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Builds from this repository use the core module of the working tree, the
+// require above pinning the core version the module is released against.
+replace github.com/benjaminch/pricers => ../..
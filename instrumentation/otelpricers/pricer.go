@@ -0,0 +1,174 @@
+// Package otelpricers instruments pricers with OpenTelemetry tracing.
+//
+// It is kept apart from pricers implementations so that they don't depend
+// on OpenTelemetry.
+package otelpricers
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/metrics"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/benjaminch/pricers/instrumentation/otelpricers"
+
+// Span attribute keys. Neither keys nor prices are ever recorded.
+const (
+	ProtocolKey  = attribute.Key("pricer.protocol")
+	KeyIDKey     = attribute.Key("pricer.key_id")
+	OperationKey = attribute.Key("pricer.operation")
+	OutcomeKey   = attribute.Key("pricer.outcome")
+)
+
+// Decrypter wraps a pricers.Decrypter, e.g. a doubleclick.DecryptOnlyPricer,
+// starting a child span for every DecryptContext call.
+type Decrypter struct {
+	decrypter pricers.Decrypter
+	settings
+}
+
+var _ pricers.Decrypter = (*Decrypter)(nil)
+
+// Encrypter wraps a pricers.Encrypter, e.g. a doubleclick.EncryptOnlyPricer,
+// starting a child span for every EncryptContext call.
+type Encrypter struct {
+	encrypter pricers.Encrypter
+	settings
+}
+
+var _ pricers.Encrypter = (*Encrypter)(nil)
+
+// Pricer wraps a pricers.Pricer, starting a child span for every
+// EncryptContext and DecryptContext call.
+type Pricer struct {
+	*Decrypter
+	*Encrypter
+}
+
+var _ pricers.Pricer = (*Pricer)(nil)
+
+// settings are the span settings of a Decrypter or an Encrypter.
+type settings struct {
+	tracer   trace.Tracer
+	protocol string
+	keyID    string
+}
+
+// Option configures a Pricer, a Decrypter or an Encrypter.
+type Option func(*settings)
+
+// WithTracerProvider sets the tracer provider, the global one being used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(s *settings) {
+		s.tracer = provider.Tracer(ScopeName)
+	}
+}
+
+// WithProtocol sets the protocol recorded on spans.
+// It defaults to doubleclick.Protocol for doubleclick pricers.
+func WithProtocol(protocol string) Option {
+	return func(s *settings) {
+		s.protocol = protocol
+	}
+}
+
+// WithKeyID sets the non secret key identifier recorded on spans.
+func WithKeyID(keyID string) Option {
+	return func(s *settings) {
+		s.keyID = keyID
+	}
+}
+
+// newSettings : Returns the span settings of a wrapped pricer.
+func newSettings(pricer interface{}, options []Option) settings {
+	s := settings{protocol: "unknown"}
+	switch pricer.(type) {
+	case *doubleclick.DoubleClickPricer, *doubleclick.DecryptOnlyPricer, *doubleclick.EncryptOnlyPricer:
+		s.protocol = doubleclick.Protocol
+	}
+	for _, option := range options {
+		option(&s)
+	}
+	if s.tracer == nil {
+		s.tracer = otel.GetTracerProvider().Tracer(ScopeName)
+	}
+	return s
+}
+
+// NewDecrypter returns a Decrypter tracing calls to decrypter.
+func NewDecrypter(decrypter pricers.Decrypter, options ...Option) *Decrypter {
+	return &Decrypter{decrypter: decrypter, settings: newSettings(decrypter, options)}
+}
+
+// NewEncrypter returns an Encrypter tracing calls to encrypter.
+func NewEncrypter(encrypter pricers.Encrypter, options ...Option) *Encrypter {
+	return &Encrypter{encrypter: encrypter, settings: newSettings(encrypter, options)}
+}
+
+// NewPricer returns a Pricer tracing calls to pricer.
+func NewPricer(pricer pricers.Pricer, options ...Option) *Pricer {
+	s := newSettings(pricer, options)
+	return &Pricer{
+		Decrypter: &Decrypter{decrypter: pricer, settings: s},
+		Encrypter: &Encrypter{encrypter: pricer, settings: s},
+	}
+}
+
+// Encrypt encrypts a clear price and a given seed, without tracing.
+func (e *Encrypter) Encrypt(seed string, price float64) (string, error) {
+	return e.encrypter.Encrypt(seed, price)
+}
+
+// Decrypt decrypts an encrypted price, without tracing.
+func (d *Decrypter) Decrypt(encryptedPrice string) (float64, error) {
+	return d.decrypter.Decrypt(encryptedPrice)
+}
+
+// EncryptContext encrypts a clear price and a given seed in a child span of ctx.
+func (e *Encrypter) EncryptContext(ctx context.Context, seed string, price float64) (string, error) {
+	_, span := e.start(ctx, metrics.Encrypt)
+	defer span.End()
+
+	encrypted, err := e.encrypter.Encrypt(seed, price)
+	end(span, err)
+	return encrypted, err
+}
+
+// DecryptContext decrypts an encrypted price in a child span of ctx.
+func (d *Decrypter) DecryptContext(ctx context.Context, encryptedPrice string) (float64, error) {
+	_, span := d.start(ctx, metrics.Decrypt)
+	defer span.End()
+
+	price, err := d.decrypter.Decrypt(encryptedPrice)
+	end(span, err)
+	return price, err
+}
+
+func (s *settings) start(ctx context.Context, operation metrics.Operation) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		ProtocolKey.String(s.protocol),
+		OperationKey.String(operation.String()),
+	}
+	if s.keyID != "" {
+		attributes = append(attributes, KeyIDKey.String(s.keyID))
+	}
+	return s.tracer.Start(ctx, "pricer."+operation.String(),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attributes...))
+}
+
+func end(span trace.Span, err error) {
+	outcome := doubleclick.OutcomeOf(err)
+	span.SetAttributes(OutcomeKey.String(outcome.String()))
+	if err != nil {
+		span.SetStatus(codes.Error, outcome.String())
+	}
+}
@@ -0,0 +1,125 @@
+package otelpricers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func buildNewTracedPricer(t *testing.T) (*Pricer, *tracetest.InMemoryExporter) {
+	pricer, err := doubleclick.NewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return NewPricer(pricer, WithTracerProvider(provider), WithKeyID("google-1")), exporter
+}
+
+func attributesOf(span tracetest.SpanStub) map[attribute.Key]string {
+	attributes := map[attribute.Key]string{}
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value.Emit()
+	}
+	return attributes
+}
+
+func TestDecryptContextStartsChildSpan(t *testing.T) {
+	// Setup:
+	pricer, exporter := buildNewTracedPricer(t)
+	ctx, parent := pricer.Decrypter.tracer.Start(context.Background(), "win-notice")
+
+	// Execute:
+	result, err := pricer.DecryptContext(ctx, "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	parent.End()
+
+	// Verify:
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "pricer.decrypt", span.Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), span.Parent.SpanID())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	assert.Equal(t, map[attribute.Key]string{
+		ProtocolKey:  "doubleclick",
+		OperationKey: "decrypt",
+		KeyIDKey:     "google-1",
+		OutcomeKey:   "ok",
+	}, attributesOf(span))
+}
+
+func TestDecryptContextRecordsFailure(t *testing.T) {
+	// Setup:
+	pricer, exporter := buildNewTracedPricer(t)
+
+	// Execute:
+	_, err := pricer.DecryptContext(context.Background(), "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+
+	// Verify:
+	assert.ErrorIs(t, err, doubleclick.ErrWrongSignature)
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "wrong_signature", attributesOf(spans[0])[OutcomeKey])
+	for _, kv := range spans[0].Attributes {
+		assert.NotContains(t, kv.Value.Emit(), "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+	}
+}
+
+func TestEncryptContextStartsSpan(t *testing.T) {
+	// Setup:
+	pricer, exporter := buildNewTracedPricer(t)
+
+	// Execute:
+	encrypted, err := pricer.EncryptContext(context.Background(), "", 1.354)
+
+	// Verify:
+	assert.Nil(t, err, "Encryption failed. Error : %s", err)
+	assert.Len(t, encrypted, 38)
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "pricer.encrypt", spans[0].Name)
+	assert.Equal(t, "ok", attributesOf(spans[0])[OutcomeKey])
+}
+
+func TestDecrypterTracesDecryptOnlyPricers(t *testing.T) {
+	// Setup:
+	pricer, err := doubleclick.NewDecryptOnlyPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	exporter := tracetest.NewInMemoryExporter()
+	decrypter := NewDecrypter(pricer, WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))))
+
+	// Execute:
+	result, err := decrypter.DecryptContext(context.Background(), "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+
+	// Verify:
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "doubleclick", attributesOf(spans[0])[ProtocolKey])
+}
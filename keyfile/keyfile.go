@@ -0,0 +1,334 @@
+// Package keyfile reads and writes key files: the encryption and integrity
+// keys of an exchange, sealed with a passphrase, so that keys can be shipped
+// without being stored in clear on disk.
+//
+// A key file is a versioned JSON envelope:
+//
+//	{
+//	  "version": 1,
+//	  "kdf": {"algorithm": "scrypt", "salt": "...", "n": 32768, "r": 8, "p": 1},
+//	  "cipher": {"algorithm": "aes-256-gcm", "nonce": "..."},
+//	  "fingerprints": {"encryption_key": "06ae61", "integrity_key": "8aa8d3"},
+//	  "ciphertext": "..."
+//	}
+//
+// The ciphertext holds the keys, how the exchange issued them and metadata,
+// encrypted with AES-256-GCM under a key derived from the passphrase with
+// scrypt. The rest of the envelope is authenticated along with it.
+package keyfile
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// Version is the key file version written, and the only one read.
+const Version = 1
+
+const (
+	// kdfScrypt is the name of the scrypt key derivation function.
+	kdfScrypt = "scrypt"
+	// cipherAESGCM is the name of the AES-256-GCM cipher.
+	cipherAESGCM = "aes-256-gcm"
+	// keySize is the size of the AES-256 key derived from the passphrase.
+	keySize = 32
+	// saltSize is the size of the scrypt salt.
+	saltSize = 16
+	// maxScryptMemory is the memory scrypt may use to open a file, 128 * N * r bytes.
+	maxScryptMemory = 1 << 30
+	// maxScryptP is the scrypt parallelization read files can't exceed.
+	maxScryptP = 16
+)
+
+// Default scrypt parameters, the ones recommended for interactive logins.
+const (
+	DefaultScryptN = 1 << 15
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+)
+
+// ErrWrongPassphrase is returned when a key file can't be opened with a passphrase,
+// either because it is not the right one or because the file was tampered with.
+var ErrWrongPassphrase = errors.New("Wrong passphrase or tampered key file")
+
+// ErrUnsupportedVersion is returned when a key file version is not Version.
+var ErrUnsupportedVersion = errors.New("Unsupported key file version")
+
+// Metadata describes sealed keys.
+type Metadata struct {
+	// Exchange is the ID of the exchange the keys were issued by.
+	Exchange string `json:"exchange,omitempty"`
+	// Comment is free text, e.g. the account the keys belong to.
+	Comment string `json:"comment,omitempty"`
+	// CreatedAt is when the keys were sealed, set by Seal if zero.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Keys are the keys of an exchange held by a key file.
+type Keys struct {
+	EncryptionKey *helpers.Key
+	IntegrityKey  *helpers.Key
+	// KeyDecodingMode and Base64Keys tell how the exchange issued the keys,
+	// see Encode.
+	KeyDecodingMode helpers.KeyDecodingMode
+	Base64Keys      bool
+	Metadata        Metadata
+}
+
+// Encode : Returns the keys in the form the exchange issued them,
+// to be given to NewDoubleClickPricer along with KeyDecodingMode and Base64Keys.
+func (k Keys) Encode() (encryptionKey string, integrityKey string) {
+	return helpers.EncodeKey(k.EncryptionKey.Bytes(), k.Base64Keys, k.KeyDecodingMode),
+		helpers.EncodeKey(k.IntegrityKey.Bytes(), k.Base64Keys, k.KeyDecodingMode)
+}
+
+// Zero overwrites both keys with zeros, see helpers.Key.Zero.
+func (k Keys) Zero() {
+	k.EncryptionKey.Zero()
+	k.IntegrityKey.Zero()
+}
+
+// envelope is the content of a key file.
+type envelope struct {
+	Version      int          `json:"version"`
+	KDF          kdf          `json:"kdf"`
+	Cipher       cipherParams `json:"cipher"`
+	Fingerprints fingerprints `json:"fingerprints"`
+	Ciphertext   []byte       `json:"ciphertext,omitempty"`
+}
+
+type kdf struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+}
+
+type cipherParams struct {
+	Algorithm string `json:"algorithm"`
+	Nonce     []byte `json:"nonce"`
+}
+
+type fingerprints struct {
+	EncryptionKey string `json:"encryption_key"`
+	IntegrityKey  string `json:"integrity_key"`
+}
+
+// payload is the plaintext sealed in a key file.
+type payload struct {
+	EncryptionKey   []byte                  `json:"encryption_key"`
+	IntegrityKey    []byte                  `json:"integrity_key"`
+	KeyDecodingMode helpers.KeyDecodingMode `json:"key_decoding_mode"`
+	Base64Keys      bool                    `json:"base64_keys"`
+	Metadata        Metadata                `json:"metadata"`
+}
+
+// sealer holds the settings of Seal.
+type sealer struct {
+	n, r, p int
+	rand    io.Reader
+}
+
+// Option is a Seal option.
+type Option func(*sealer)
+
+// WithScryptParams sets the scrypt cost parameters, DefaultScryptN,
+// DefaultScryptR and DefaultScryptP being used by default. Seal fails if
+// they are out of the bounds Open accepts.
+func WithScryptParams(n int, r int, p int) Option {
+	return func(s *sealer) {
+		s.n, s.r, s.p = n, r, p
+	}
+}
+
+// WithRand sets the source of salts and nonces, crypto/rand by default.
+func WithRand(r io.Reader) Option {
+	return func(s *sealer) {
+		s.rand = r
+	}
+}
+
+// Seal returns the key file holding keys, sealed with passphrase.
+func Seal(keys Keys, passphrase []byte, options ...Option) ([]byte, error) {
+	s := sealer{n: DefaultScryptN, r: DefaultScryptR, p: DefaultScryptP, rand: rand.Reader}
+	for _, option := range options {
+		option(&s)
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("keyfile: passphrase is empty")
+	}
+	// Files Open would reject are not written.
+	if err := checkScryptParams(s.n, s.r, s.p); err != nil {
+		return nil, err
+	}
+	if keys.EncryptionKey.Len() == 0 || keys.IntegrityKey.Len() == 0 {
+		return nil, fmt.Errorf("keyfile: keys are empty")
+	}
+	if keys.Metadata.CreatedAt.IsZero() {
+		keys.Metadata.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	env := envelope{
+		Version: Version,
+		KDF:     kdf{Algorithm: kdfScrypt, Salt: make([]byte, saltSize), N: s.n, R: s.r, P: s.p},
+		Cipher:  cipherParams{Algorithm: cipherAESGCM},
+		Fingerprints: fingerprints{
+			EncryptionKey: keys.EncryptionKey.Fingerprint(),
+			IntegrityKey:  keys.IntegrityKey.Fingerprint(),
+		},
+	}
+	if _, err := io.ReadFull(s.rand, env.KDF.Salt); err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	aead, err := newAEAD(env.KDF, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	env.Cipher.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(s.rand, env.Cipher.Nonce); err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+
+	plaintext, err := json.Marshal(payload{
+		EncryptionKey:   keys.EncryptionKey.Bytes(),
+		IntegrityKey:    keys.IntegrityKey.Bytes(),
+		KeyDecodingMode: keys.KeyDecodingMode,
+		Base64Keys:      keys.Base64Keys,
+		Metadata:        keys.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	defer clear(plaintext)
+	header, err := env.header()
+	if err != nil {
+		return nil, err
+	}
+	env.Ciphertext = aead.Seal(nil, env.Cipher.Nonce, plaintext, header)
+
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Open returns the keys held by a key file, sealed with passphrase.
+// ErrWrongPassphrase is returned if the passphrase is not the right one.
+func Open(data []byte, passphrase []byte) (Keys, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Keys{}, fmt.Errorf("keyfile: %w", err)
+	}
+	if env.Version != Version {
+		return Keys{}, fmt.Errorf("keyfile: %w: %d", ErrUnsupportedVersion, env.Version)
+	}
+	if env.Cipher.Algorithm != cipherAESGCM {
+		return Keys{}, fmt.Errorf("keyfile: unsupported cipher %q", env.Cipher.Algorithm)
+	}
+	// Bound the cost of opening untrusted files.
+	if err := checkScryptParams(env.KDF.N, env.KDF.R, env.KDF.P); err != nil {
+		return Keys{}, err
+	}
+	aead, err := newAEAD(env.KDF, passphrase)
+	if err != nil {
+		return Keys{}, err
+	}
+	if len(env.Cipher.Nonce) != aead.NonceSize() {
+		return Keys{}, fmt.Errorf("keyfile: nonce has %d bytes instead of %d", len(env.Cipher.Nonce), aead.NonceSize())
+	}
+	header, err := env.header()
+	if err != nil {
+		return Keys{}, err
+	}
+	plaintext, err := aead.Open(nil, env.Cipher.Nonce, env.Ciphertext, header)
+	if err != nil {
+		return Keys{}, ErrWrongPassphrase
+	}
+	defer clear(plaintext)
+
+	var p payload
+	if err := json.Unmarshal(plaintext, &p); err != nil {
+		return Keys{}, fmt.Errorf("keyfile: %w", err)
+	}
+	defer clear(p.EncryptionKey)
+	defer clear(p.IntegrityKey)
+	if _, err := helpers.ParseKeyDecodingMode(p.KeyDecodingMode.String()); err != nil {
+		return Keys{}, fmt.Errorf("keyfile: key decoding mode: %w", err)
+	}
+	return Keys{
+		EncryptionKey:   helpers.NewKey(p.EncryptionKey),
+		IntegrityKey:    helpers.NewKey(p.IntegrityKey),
+		KeyDecodingMode: p.KeyDecodingMode,
+		Base64Keys:      p.Base64Keys,
+		Metadata:        p.Metadata,
+	}, nil
+}
+
+// Rotate returns the key file sealed with oldPassphrase, sealed with
+// newPassphrase instead. Keys and metadata are left untouched.
+func Rotate(data []byte, oldPassphrase []byte, newPassphrase []byte, options ...Option) ([]byte, error) {
+	keys, err := Open(data, oldPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer keys.Zero()
+	return Seal(keys, newPassphrase, options...)
+}
+
+// Load returns the keys held by the key file at path, sealed with passphrase.
+func Load(path string, passphrase []byte) (Keys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Keys{}, err
+	}
+	keys, err := Open(data, passphrase)
+	if err != nil {
+		return Keys{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// checkScryptParams : Returns an error if the scrypt parameters are out of
+// the bounds Open accepts, N being a power of 2 greater than 1.
+func checkScryptParams(n int, r int, p int) error {
+	if n <= 1 || n&(n-1) != 0 || r <= 0 || p <= 0 || n > maxScryptMemory/128/r || p > maxScryptP {
+		return fmt.Errorf("keyfile: scrypt parameters N=%d, r=%d, p=%d are out of bounds", n, r, p)
+	}
+	return nil
+}
+
+// header : Returns the authenticated data of an envelope, its JSON encoding without ciphertext.
+func (env envelope) header() ([]byte, error) {
+	env.Ciphertext = nil
+	header, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	return header, nil
+}
+
+// newAEAD : Returns AES-256-GCM keyed with the key derived from passphrase.
+func newAEAD(params kdf, passphrase []byte) (cipher.AEAD, error) {
+	if params.Algorithm != kdfScrypt {
+		return nil, fmt.Errorf("keyfile: unsupported key derivation function %q", params.Algorithm)
+	}
+	key, err := scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: scrypt: %w", err)
+	}
+	defer clear(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,221 @@
+package keyfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+const (
+	encryptionKey = "ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU"
+	integrityKey  = "vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U"
+	passphrase    = "correct horse battery staple"
+)
+
+// fastScrypt makes tests fast, at the expense of security.
+var fastScrypt = WithScryptParams(1024, 8, 1)
+
+func buildKeys(t *testing.T) Keys {
+	t.Helper()
+	encryption, err := helpers.ParseKey(encryptionKey, true, helpers.Utf8)
+	assert.Nil(t, err)
+	integrity, err := helpers.ParseKey(integrityKey, true, helpers.Utf8)
+	assert.Nil(t, err)
+	return Keys{
+		EncryptionKey:   encryption,
+		IntegrityKey:    integrity,
+		KeyDecodingMode: helpers.Utf8,
+		Base64Keys:      true,
+		Metadata:        Metadata{Exchange: "adx", Comment: "Google test keys", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	// Setup:
+	keys := buildKeys(t)
+
+	// Execute:
+	data, err := Seal(keys, []byte(passphrase), fastScrypt)
+	assert.Nil(t, err)
+	opened, err := Open(data, []byte(passphrase))
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.True(t, keys.EncryptionKey.Equal(*opened.EncryptionKey))
+	assert.True(t, keys.IntegrityKey.Equal(*opened.IntegrityKey))
+	assert.Equal(t, keys.Metadata, opened.Metadata)
+	encryption, integrity := opened.Encode()
+	assert.Equal(t, encryptionKey, encryption)
+	assert.Equal(t, integrityKey, integrity)
+	assert.NotContains(t, string(data), encryptionKey)
+	assert.Contains(t, string(data), `"encryption_key": "06ae61"`)
+}
+
+func TestSealUsesFreshSaltAndNonce(t *testing.T) {
+	// Setup:
+	keys := buildKeys(t)
+
+	// Execute:
+	first, err := Seal(keys, []byte(passphrase), fastScrypt)
+	assert.Nil(t, err)
+	second, err := Seal(keys, []byte(passphrase), fastScrypt)
+	assert.Nil(t, err)
+
+	// Verify:
+	assert.NotEqual(t, first, second)
+}
+
+func TestSealRejectsEmptyPassphrase(t *testing.T) {
+	// Execute:
+	_, err := Seal(buildKeys(t), nil, fastScrypt)
+
+	// Verify:
+	assert.EqualError(t, err, "keyfile: passphrase is empty")
+}
+
+func TestOpenWrongPassphrase(t *testing.T) {
+	// Setup:
+	data, err := Seal(buildKeys(t), []byte(passphrase), fastScrypt)
+	assert.Nil(t, err)
+
+	// Execute:
+	_, err = Open(data, []byte("wrong"))
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+}
+
+func TestOpenTamperedHeader(t *testing.T) {
+	// Setup:
+	data, err := Seal(buildKeys(t), []byte(passphrase), fastScrypt)
+	assert.Nil(t, err)
+	// The header is authenticated: fingerprints can't be swapped for other keys ones.
+	tampered := bytes.Replace(data, []byte(`"06ae61"`), []byte(`"000000"`), 1)
+
+	// Execute:
+	_, err = Open(tampered, []byte(passphrase))
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+}
+
+func TestOpenRejectsMalformedFiles(t *testing.T) {
+	// Setup:
+	data, err := os.ReadFile("testdata/adx.keys")
+	assert.Nil(t, err)
+	var env map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &env))
+
+	tests := []struct {
+		name   string
+		modify func(env map[string]interface{})
+		err    string
+	}{
+		{"version", func(env map[string]interface{}) { env["version"] = 2 }, "keyfile: Unsupported key file version: 2"},
+		{"cipher", func(env map[string]interface{}) {
+			env["cipher"].(map[string]interface{})["algorithm"] = "des"
+		}, `keyfile: unsupported cipher "des"`},
+		{"kdf", func(env map[string]interface{}) {
+			env["kdf"].(map[string]interface{})["algorithm"] = "md5"
+		}, `keyfile: unsupported key derivation function "md5"`},
+		{"cost", func(env map[string]interface{}) {
+			env["kdf"].(map[string]interface{})["n"] = 1 << 24
+		}, "keyfile: scrypt parameters N=16777216, r=8, p=1 are out of bounds"},
+		{"nonce", func(env map[string]interface{}) {
+			env["cipher"].(map[string]interface{})["nonce"] = "BwcH"
+		}, "keyfile: nonce has 3 bytes instead of 12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup:
+			var modified map[string]interface{}
+			assert.Nil(t, json.Unmarshal(data, &modified))
+			tt.modify(modified)
+			content, err := json.Marshal(modified)
+			assert.Nil(t, err)
+
+			// Execute:
+			_, err = Open(content, []byte(passphrase))
+
+			// Verify:
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestSealRejectsOutOfBoundsScryptParams(t *testing.T) {
+	tests := []struct {
+		n, r, p int
+	}{
+		{1 << 24, 8, 1},
+		{1000, 8, 1},
+		{1, 8, 1},
+		{1024, 0, 1},
+		{1024, 8, 17},
+	}
+	for _, tt := range tests {
+		// Execute:
+		data, err := Seal(buildKeys(t), []byte(passphrase), WithScryptParams(tt.n, tt.r, tt.p))
+
+		// Verify:
+		assert.Nil(t, data)
+		assert.EqualError(t, err, fmt.Sprintf("keyfile: scrypt parameters N=%d, r=%d, p=%d are out of bounds", tt.n, tt.r, tt.p))
+	}
+}
+
+func TestRotate(t *testing.T) {
+	// Setup:
+	data, err := Seal(buildKeys(t), []byte(passphrase), fastScrypt)
+	assert.Nil(t, err)
+
+	// Execute:
+	rotated, err := Rotate(data, []byte(passphrase), []byte("new passphrase"), fastScrypt)
+	assert.Nil(t, err)
+	_, oldErr := Open(rotated, []byte(passphrase))
+	keys, newErr := Open(rotated, []byte("new passphrase"))
+
+	// Verify:
+	assert.ErrorIs(t, oldErr, ErrWrongPassphrase)
+	assert.Nil(t, newErr)
+	assert.Equal(t, "06ae61", keys.EncryptionKey.Fingerprint())
+	assert.Equal(t, buildKeys(t).Metadata, keys.Metadata)
+}
+
+func TestLoad(t *testing.T) {
+	// Execute:
+	keys, err := Load("testdata/adx.keys", []byte(passphrase))
+	_, wrongErr := Load("testdata/adx.keys", []byte("wrong"))
+	_, missingErr := Load(filepath.Join(t.TempDir(), "missing.keys"), []byte(passphrase))
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "06ae61", keys.EncryptionKey.Fingerprint())
+	assert.Equal(t, "8aa8d3", keys.IntegrityKey.Fingerprint())
+	assert.Equal(t, helpers.Utf8, keys.KeyDecodingMode)
+	assert.True(t, keys.Base64Keys)
+	assert.Equal(t, "adx", keys.Metadata.Exchange)
+	assert.ErrorIs(t, wrongErr, ErrWrongPassphrase)
+	assert.True(t, strings.HasPrefix(wrongErr.Error(), "testdata/adx.keys: "))
+	assert.True(t, os.IsNotExist(missingErr))
+}
+
+func TestKeysZero(t *testing.T) {
+	// Setup:
+	keys := buildKeys(t)
+
+	// Execute:
+	keys.Zero()
+
+	// Verify:
+	assert.Equal(t, 0, keys.EncryptionKey.Len())
+	assert.Equal(t, 0, keys.IntegrityKey.Len())
+}
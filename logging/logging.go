@@ -0,0 +1,55 @@
+// Package logging provides the structured logger pricers emit their debug
+// events to, along with a log/slog adapter.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// Field is a key value pair attached to a debug event.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F : Returns a Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger receives pricers debug events.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+}
+
+// SlogLogger is a Logger writing debug events to a slog.Logger.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing debug events to logger.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{logger: logger}
+}
+
+// NewStderrLogger returns a Logger writing debug events as text to stderr,
+// used in debug mode when no logger is given.
+func NewStderrLogger() *SlogLogger {
+	return NewSlogLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+// Debug : Writes a debug event.
+func (l *SlogLogger) Debug(msg string, fields ...Field) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+	l.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
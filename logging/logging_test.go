@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	// Setup:
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	// Execute:
+	logger.Debug("event", F("iv", "00ff"))
+
+	// Verify:
+	var event map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "event", event["msg"])
+	assert.Equal(t, "DEBUG", event["level"])
+	assert.Equal(t, "00ff", event["iv"])
+}
+
+func TestSlogLoggerDisabled(t *testing.T) {
+	// Setup:
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	// Execute:
+	logger.Debug("event", F("iv", "00ff"))
+
+	// Verify:
+	assert.Empty(t, buf.String())
+}
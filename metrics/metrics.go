@@ -0,0 +1,77 @@
+// Package metrics defines the observer pricers report their operations to.
+package metrics
+
+import (
+	"time"
+)
+
+// Operation : Describing which pricer operation was observed.
+type Operation string
+
+// String : Returns the Operation string representation.
+func (o Operation) String() string {
+	return string(o)
+}
+
+const (
+	// Encrypt : Price encryption.
+	Encrypt Operation = "encrypt"
+	// Decrypt : Price decryption.
+	Decrypt Operation = "decrypt"
+)
+
+// Outcome : Describing how an operation ended.
+type Outcome string
+
+// String : Returns the Outcome string representation.
+func (o Outcome) String() string {
+	return string(o)
+}
+
+const (
+	// OK : Operation succeeded.
+	OK Outcome = "ok"
+	// WrongSize : Encrypted price has a wrong size.
+	WrongSize Outcome = "wrong_size"
+	// BadBase64 : Encrypted price is not valid base64.
+	BadBase64 Outcome = "bad_base64"
+	// WrongSignature : Encrypted price signature doesn't match.
+	WrongSignature Outcome = "wrong_signature"
+	// KeyMismatch : Encrypted price was issued for other keys.
+	KeyMismatch Outcome = "key_mismatch"
+	// OutOfRange : Price is out of range.
+	OutOfRange Outcome = "out_of_range"
+	// UnknownSeat : No pricer is configured for the seat, see doubleclick.Router.
+	UnknownSeat Outcome = "unknown_seat"
+	// Error : Operation failed for another reason.
+	Error Outcome = "error"
+)
+
+// Event describes an observed pricer operation.
+type Event struct {
+	Protocol string
+	// Exchange is the exchange name the pricer was given, e.g. with
+	// doubleclick.WithExchange, and is empty otherwise.
+	Exchange  string
+	Operation Operation
+	Outcome   Outcome
+	Latency   time.Duration
+	// Seat is the seat, or buyer account, ID of routed operations, and is
+	// empty otherwise.
+	Seat string
+}
+
+// Observer is notified of every pricer operation.
+// Implementations must be safe for concurrent use and should be fast,
+// as they are called on the encryption and decryption hot path.
+type Observer interface {
+	Observe(event Event)
+}
+
+// ObserverFunc is an adapter allowing a function to be used as an Observer.
+type ObserverFunc func(event Event)
+
+// Observe : Calls f(event).
+func (f ObserverFunc) Observe(event Event) {
+	f(event)
+}
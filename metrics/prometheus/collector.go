@@ -0,0 +1,96 @@
+// Package prometheus exposes pricers metrics to Prometheus.
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/benjaminch/pricers/metrics"
+)
+
+// Collector is a metrics.Observer exposing pricer operations as
+// Prometheus counters and latency histograms.
+//
+// Exported metrics are:
+//   - <namespace>_pricer_operations_total{protocol,exchange,operation,outcome}
+//   - <namespace>_pricer_operation_duration_seconds{protocol,exchange,operation}
+//
+// With WithSeatLabel, both metrics also have a seat label.
+type Collector struct {
+	operations *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+	seatLabel  bool
+}
+
+// Option is a Collector option.
+type Option func(*Collector)
+
+// WithSeatLabel adds a seat label to the metrics, for the events of a
+// doubleclick.Router. Routers only report configured seats, bounding its cardinality.
+func WithSeatLabel() Option {
+	return func(c *Collector) {
+		c.seatLabel = true
+	}
+}
+
+var (
+	_ metrics.Observer     = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// NewCollector returns a Collector whose metrics are prefixed with namespace.
+// It must be registered, e.g. with prometheus.MustRegister.
+func NewCollector(namespace string, options ...Option) *Collector {
+	c := &Collector{}
+	for _, option := range options {
+		option(c)
+	}
+	operationLabels := []string{"protocol", "exchange", "operation", "outcome"}
+	durationLabels := []string{"protocol", "exchange", "operation"}
+	if c.seatLabel {
+		operationLabels = append(operationLabels, "seat")
+		durationLabels = append(durationLabels, "seat")
+	}
+	c.operations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "pricer",
+			Name:      "operations_total",
+			Help:      "Number of price encryptions and decryptions, by outcome.",
+		},
+		operationLabels,
+	)
+	c.durations = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "pricer",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of price encryptions and decryptions.",
+			Buckets:   prometheus.ExponentialBuckets(1e-7, 4, 10),
+		},
+		durationLabels,
+	)
+	return c
+}
+
+// Observe : Records a pricer operation.
+func (c *Collector) Observe(event metrics.Event) {
+	if c.seatLabel {
+		c.operations.WithLabelValues(event.Protocol, event.Exchange, event.Operation.String(), event.Outcome.String(), event.Seat).Inc()
+		c.durations.WithLabelValues(event.Protocol, event.Exchange, event.Operation.String(), event.Seat).Observe(event.Latency.Seconds())
+		return
+	}
+	c.operations.WithLabelValues(event.Protocol, event.Exchange, event.Operation.String(), event.Outcome.String()).Inc()
+	c.durations.WithLabelValues(event.Protocol, event.Exchange, event.Operation.String()).Observe(event.Latency.Seconds())
+}
+
+// Describe : Implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.operations.Describe(ch)
+	c.durations.Describe(ch)
+}
+
+// Collect : Implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.operations.Collect(ch)
+	c.durations.Collect(ch)
+}
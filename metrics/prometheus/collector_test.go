@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func TestCollectorRecordsDoubleClickOutcomes(t *testing.T) {
+	// Setup:
+	collector := NewCollector("test")
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(collector))
+
+	pricer, err := doubleclick.NewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+		doubleclick.WithObserver(collector),
+		doubleclick.WithExchange("adx"),
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+
+	// Execute:
+	_, _ = pricer.Encrypt("", 1)
+	_, _ = pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	_, _ = pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+	_, _ = pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+	_, _ = pricer.Decrypt("too short")
+	_, _ = pricer.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lX!!")
+
+	// Verify:
+	expected := `
+# HELP test_pricer_operations_total Number of price encryptions and decryptions, by outcome.
+# TYPE test_pricer_operations_total counter
+test_pricer_operations_total{exchange="adx",operation="decrypt",outcome="bad_base64",protocol="doubleclick"} 1
+test_pricer_operations_total{exchange="adx",operation="decrypt",outcome="ok",protocol="doubleclick"} 1
+test_pricer_operations_total{exchange="adx",operation="decrypt",outcome="wrong_signature",protocol="doubleclick"} 2
+test_pricer_operations_total{exchange="adx",operation="decrypt",outcome="wrong_size",protocol="doubleclick"} 1
+test_pricer_operations_total{exchange="adx",operation="encrypt",outcome="ok",protocol="doubleclick"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected), "test_pricer_operations_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector, "test_pricer_operation_duration_seconds"))
+}
+
+func TestCollectorWithSeatLabel(t *testing.T) {
+	// Setup:
+	collector := NewCollector("test", WithSeatLabel())
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(collector))
+
+	pricer, err := doubleclick.NewDoubleClickPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+		doubleclick.WithExchange("adx"),
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	router, err := doubleclick.NewRouter(
+		map[string]pricers.Decrypter{"1234": pricer},
+		doubleclick.WithRouterObserver(collector),
+	)
+	assert.Nil(t, err)
+
+	// Execute:
+	_, _ = router.Decrypt("1234", "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	_, _ = router.Decrypt("5678", "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+
+	// Verify:
+	expected := `
+# HELP test_pricer_operations_total Number of price encryptions and decryptions, by outcome.
+# TYPE test_pricer_operations_total counter
+test_pricer_operations_total{exchange="adx",operation="decrypt",outcome="ok",protocol="doubleclick",seat="1234"} 1
+test_pricer_operations_total{exchange="",operation="decrypt",outcome="unknown_seat",protocol="doubleclick",seat=""} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected), "test_pricer_operations_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector, "test_pricer_operation_duration_seconds"))
+}
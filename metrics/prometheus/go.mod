@@ -0,0 +1,28 @@
+module github.com/benjaminch/pricers/metrics/prometheus
+
+go 1.21
+
+require (
+	github.com/benjaminch/pricers v0.0.0-20261019022350-7a6f9fc5083a
+	github.com/prometheus/client_golang v1.21.1
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.62.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	google.golang.org/protobuf v1.36.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Builds from this repository use the core module of the working tree, the
+// require above pinning the core version the module is released against.
+replace github.com/benjaminch/pricers => ../..
@@ -0,0 +1,189 @@
+package openrtb
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/benjaminch/pricers"
+)
+
+// Macro : OpenRTB 2.x auction macro name, without the `${` `}` delimiters.
+type Macro string
+
+// String : Returns the Macro string representation.
+func (m Macro) String() string {
+	return string(m)
+}
+
+const (
+	// AuctionID : ID of the bid request.
+	AuctionID Macro = "AUCTION_ID"
+	// AuctionBidID : ID of the bid.
+	AuctionBidID Macro = "AUCTION_BID_ID"
+	// AuctionImpID : ID of the impression just won.
+	AuctionImpID Macro = "AUCTION_IMP_ID"
+	// AuctionSeatID : ID of the bidder seat for whom the bid was made.
+	AuctionSeatID Macro = "AUCTION_SEAT_ID"
+	// AuctionAdID : ID of the ad markup the bidder wishes to serve.
+	AuctionAdID Macro = "AUCTION_AD_ID"
+	// AuctionPrice : Clearing price, carried encrypted.
+	AuctionPrice Macro = "AUCTION_PRICE"
+	// AuctionCurrency : Currency used in the bid.
+	AuctionCurrency Macro = "AUCTION_CURRENCY"
+	// AuctionMBR : Market bid ratio.
+	AuctionMBR Macro = "AUCTION_MBR"
+	// AuctionLoss : Loss reason code.
+	AuctionLoss Macro = "AUCTION_LOSS"
+	// AuctionMinToWin : Minimum bid to win the exchange's auction, carried encrypted.
+	AuctionMinToWin Macro = "AUCTION_MIN_TO_WIN"
+)
+
+// ErrEmptySeed is returned when encrypting prices of an auction with neither a seed nor an ID.
+var ErrEmptySeed = errors.New("Auction has neither a seed nor an ID to encrypt prices with")
+
+// B64Suffix : Suffix asking for the macro value to be base64 encoded.
+const B64Suffix = ":B64"
+
+// IsPrice : Tells whether the macro carries a price that should be encrypted.
+func (m Macro) IsPrice() bool {
+	return m == AuctionPrice || m == AuctionMinToWin
+}
+
+// Auction holds the values substituted to auction macros.
+type Auction struct {
+	ID       string
+	BidID    string
+	ImpID    string
+	SeatID   string
+	AdID     string
+	Price    float64
+	Currency string
+	MBR      float64
+	Loss     int
+	MinToWin float64
+	// Seed is used to encrypt prices, ID is used when empty. Each price macro
+	// derives its own seed from it, so that prices don't share an IV.
+	Seed string
+}
+
+// Expander substitutes OpenRTB auction macros in notice URLs (nurl, burl, lurl)
+// and ad markups (adm).
+type Expander struct {
+	pricer pricers.Encrypter
+}
+
+// NewExpander returns an Expander encrypting price macros with the given pricer,
+// e.g. a doubleclick.EncryptOnlyPricer. If pricer is nil, prices are substituted in clear.
+func NewExpander(pricer pricers.Encrypter) *Expander {
+	return &Expander{pricer: pricer}
+}
+
+// Expand substitutes every auction macro found in s with values from auction.
+// Both `${MACRO}` and `${MACRO:B64}` forms are supported, unknown macros are
+// left untouched. Values are escaped with url.QueryEscape, so that they can't
+// add query parameters to notice URLs.
+func (e *Expander) Expand(s string, auction Auction) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		// A stray `${` doesn't hide the macro following it.
+		start += strings.LastIndex(s[start:end], "${")
+
+		b.WriteString(s[:start])
+		name := s[start+2 : end]
+		value, ok, err := e.value(name, auction)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			b.WriteString(url.QueryEscape(value))
+		} else {
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+
+	return b.String(), nil
+}
+
+// value returns the substitution for a macro name, possibly suffixed by B64Suffix.
+func (e *Expander) value(name string, auction Auction) (string, bool, error) {
+	isB64 := strings.HasSuffix(name, B64Suffix)
+	macro := Macro(strings.TrimSuffix(name, B64Suffix))
+
+	var value string
+	switch macro {
+	case AuctionID:
+		value = auction.ID
+	case AuctionBidID:
+		value = auction.BidID
+	case AuctionImpID:
+		value = auction.ImpID
+	case AuctionSeatID:
+		value = auction.SeatID
+	case AuctionAdID:
+		value = auction.AdID
+	case AuctionCurrency:
+		value = auction.Currency
+	case AuctionMBR:
+		value = formatFloat(auction.MBR)
+	case AuctionLoss:
+		value = strconv.Itoa(auction.Loss)
+	case AuctionPrice, AuctionMinToWin:
+		price := auction.Price
+		if macro == AuctionMinToWin {
+			price = auction.MinToWin
+		}
+		var err error
+		if value, err = e.encrypt(macro, price, auction); err != nil {
+			return "", false, err
+		}
+	default:
+		return "", false, nil
+	}
+
+	if isB64 {
+		value = base64.URLEncoding.EncodeToString([]byte(value))
+	}
+
+	return value, true, nil
+}
+
+// encrypt encrypts the price of a macro with the expander pricer, if any.
+// The seed is suffixed with the macro name: prices encrypted with the same
+// seed share an IV and a pad, XOR-ing their tokens leaking the XOR of prices.
+func (e *Expander) encrypt(macro Macro, price float64, auction Auction) (string, error) {
+	if e.pricer == nil {
+		return formatFloat(price), nil
+	}
+	seed := auction.Seed
+	if seed == "" {
+		seed = auction.ID
+	}
+	if seed == "" {
+		return "", ErrEmptySeed
+	}
+	return e.pricer.Encrypt(seed+"/"+macro.String(), price)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
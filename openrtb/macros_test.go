@@ -0,0 +1,174 @@
+package openrtb
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+func buildNewDoubleClickPricer(t *testing.T) *doubleclick.DoubleClickPricer {
+	pricer, err := doubleclick.NewDoubleClickPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	return pricer
+}
+
+func TestExpandAllMacros(t *testing.T) {
+	// Setup:
+	expander := NewExpander(buildNewDoubleClickPricer(t))
+	auction := Auction{
+		ID:       "req-1",
+		BidID:    "bid-1",
+		ImpID:    "imp-1",
+		SeatID:   "seat-1",
+		AdID:     "ad-1",
+		Price:    1.354,
+		Currency: "USD",
+		MBR:      0.25,
+		Loss:     102,
+		MinToWin: 1.354,
+	}
+
+	// Execute:
+	result, err := expander.Expand("https://example.com/win?id=${AUCTION_ID}&bid=${AUCTION_BID_ID}&imp=${AUCTION_IMP_ID}&seat=${AUCTION_SEAT_ID}&ad=${AUCTION_AD_ID}&cur=${AUCTION_CURRENCY}&mbr=${AUCTION_MBR}&loss=${AUCTION_LOSS}", auction)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/win?id=req-1&bid=bid-1&imp=imp-1&seat=seat-1&ad=ad-1&cur=USD&mbr=0.25&loss=102", result)
+}
+
+func TestExpandEncryptsPrices(t *testing.T) {
+	// Setup:
+	pricer := buildNewDoubleClickPricer(t)
+	expander := NewExpander(pricer)
+	auction := Auction{ID: "req-1", Price: 1.354, MinToWin: 0.01}
+
+	// Execute:
+	result, err := expander.Expand("${AUCTION_PRICE}&${AUCTION_MIN_TO_WIN}", auction)
+
+	// Verify:
+	assert.Nil(t, err)
+	tokens := strings.Split(result, "&")
+	assert.Len(t, tokens, 2)
+	price, err := pricer.Decrypt(tokens[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, price)
+	minToWin, err := pricer.Decrypt(tokens[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0.01, minToWin)
+	// Each price has its own IV, 16 bytes being 21 base64 characters and a third.
+	assert.NotEqual(t, tokens[0][:21], tokens[1][:21])
+}
+
+func TestExpandWithEncryptOnlyPricer(t *testing.T) {
+	// Setup:
+	encrypter, err := doubleclick.NewEncryptOnlyPricer(
+		"652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135",
+		"bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5",
+		false, // Keys are not base64
+		helpers.Hexa,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	expander := NewExpander(encrypter)
+
+	// Execute:
+	result, err := expander.Expand("${AUCTION_PRICE}", Auction{ID: "req-1", Price: 1.354})
+
+	// Verify:
+	assert.Nil(t, err)
+	price, err := buildNewDoubleClickPricer(t).Decrypt(result)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.354, price)
+}
+
+func TestExpandRejectsEmptySeed(t *testing.T) {
+	// Setup:
+	expander := NewExpander(buildNewDoubleClickPricer(t))
+
+	// Execute:
+	_, err := expander.Expand("p=${AUCTION_PRICE}", Auction{Price: 1.354})
+	clearPrice, clearErr := NewExpander(nil).Expand("p=${AUCTION_PRICE}", Auction{Price: 1.354})
+
+	// Verify:
+	assert.ErrorIs(t, err, ErrEmptySeed)
+	assert.Nil(t, clearErr)
+	assert.Equal(t, "p=1.354", clearPrice)
+}
+
+func TestExpandB64Macros(t *testing.T) {
+	// Setup:
+	pricer := buildNewDoubleClickPricer(t)
+	expander := NewExpander(pricer)
+	auction := Auction{ID: "req-1", Price: 1.354}
+	encrypted, err := pricer.Encrypt("req-1/AUCTION_PRICE", 1.354)
+	assert.Nil(t, err)
+
+	// Execute:
+	result, err := expander.Expand("${AUCTION_ID:B64}/${AUCTION_PRICE:B64}", auction)
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, url.QueryEscape(base64.URLEncoding.EncodeToString([]byte("req-1")))+"/"+url.QueryEscape(base64.URLEncoding.EncodeToString([]byte(encrypted))), result)
+}
+
+func TestExpandEscapesValues(t *testing.T) {
+	// Setup:
+	expander := NewExpander(nil)
+
+	// Execute:
+	result, err := expander.Expand("https://example.com/win?x=1&y=${AUCTION_ID}", Auction{ID: "a&b=c d"})
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/win?x=1&y=a%26b%3Dc+d", result)
+}
+
+func TestExpandAfterStrayDelimiter(t *testing.T) {
+	// Setup:
+	expander := NewExpander(nil)
+
+	// Execute:
+	result, err := expander.Expand("x=${FOO ${AUCTION_PRICE}&y=${${AUCTION_ID}}", Auction{ID: "req-1", Price: 1.354})
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "x=${FOO 1.354&y=${req-1}", result)
+}
+
+func TestExpandWithoutPricer(t *testing.T) {
+	// Setup:
+	expander := NewExpander(nil)
+
+	// Execute:
+	result, err := expander.Expand("<img src=\"https://example.com/imp?p=${AUCTION_PRICE}\">", Auction{Price: 3.24})
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "<img src=\"https://example.com/imp?p=3.24\">", result)
+}
+
+func TestExpandKeepsUnknownMacros(t *testing.T) {
+	// Setup:
+	expander := NewExpander(nil)
+
+	// Execute:
+	result, err := expander.Expand("a=${UNKNOWN}&b=${AUCTION_ID}&c=${unterminated", Auction{ID: "req-1"})
+
+	// Verify:
+	assert.Nil(t, err)
+	assert.Equal(t, "a=${UNKNOWN}&b=req-1&c=${unterminated", result)
+}
@@ -0,0 +1,59 @@
+package openrtb
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/benjaminch/pricers"
+)
+
+// ErrMissingPrice is returned when the notice URL doesn't carry the price parameter.
+var ErrMissingPrice = errors.New("Notice URL has no price parameter")
+
+// ErrUnexpandedMacro is returned when the exchange didn't substitute the price macro.
+var ErrUnexpandedMacro = errors.New("Notice URL price macro was not substituted")
+
+// ExtractPrice returns the encrypted price carried by param in a notice URL.
+// If isB64 is true, the value is expected to be base64 encoded, as requested
+// by a `${AUCTION_PRICE:B64}` macro.
+func ExtractPrice(rawURL string, param string, isB64 bool) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	encryptedPrice := values.Get(param)
+	if encryptedPrice == "" {
+		return "", ErrMissingPrice
+	}
+	if strings.HasPrefix(encryptedPrice, "${") {
+		return "", ErrUnexpandedMacro
+	}
+
+	if isB64 {
+		decoded, err := base64.URLEncoding.DecodeString(encryptedPrice)
+		if err != nil {
+			return "", err
+		}
+		encryptedPrice = string(decoded)
+	}
+
+	return encryptedPrice, nil
+}
+
+// DecryptPrice extracts the encrypted price carried by param in a notice URL
+// and decrypts it with the given pricer, e.g. a doubleclick.Router seat.
+func DecryptPrice(pricer pricers.Decrypter, rawURL string, param string, isB64 bool) (float64, error) {
+	encryptedPrice, err := ExtractPrice(rawURL, param, isB64)
+	if err != nil {
+		return 0, err
+	}
+	return pricer.Decrypt(encryptedPrice)
+}
@@ -0,0 +1,43 @@
+package openrtb
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptPrice(t *testing.T) {
+	// Setup:
+	pricer := buildNewDoubleClickPricer(t)
+
+	// Execute:
+	result, err := DecryptPrice(pricer, "https://example.com/win?imp=1&price=1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA", "price", false)
+
+	// Verify:
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+}
+
+func TestDecryptPriceB64(t *testing.T) {
+	// Setup:
+	pricer := buildNewDoubleClickPricer(t)
+	encoded := base64.URLEncoding.EncodeToString([]byte("1B2M2Y8AsgTpgAmY7PhCfgDo9mJGavHOuu-2SA"))
+
+	// Execute:
+	result, err := DecryptPrice(pricer, "https://example.com/win?price="+encoded, "price", true)
+
+	// Verify:
+	assert.Nil(t, err, "Decryption failed. Error : %s", err)
+	assert.InDelta(t, 1.354, result, 0.001)
+}
+
+func TestExtractPriceErrors(t *testing.T) {
+	// Execute:
+	_, errMissing := ExtractPrice("https://example.com/win?imp=1", "price", false)
+	_, errUnexpanded := ExtractPrice("https://example.com/win?price=${AUCTION_PRICE}", "price", false)
+
+	// Verify:
+	assert.Equal(t, ErrMissingPrice, errMissing)
+	assert.Equal(t, ErrUnexpandedMacro, errUnexpanded)
+}
@@ -0,0 +1,22 @@
+package pricers
+
+// Pricer is implemented by every price encryption protocol
+// supported by this library.
+type Pricer interface {
+	Encrypter
+	Decrypter
+}
+
+// Encrypter encrypts prices. Exchanges issuing encrypted prices only need
+// an Encrypter, which can't decrypt them.
+type Encrypter interface {
+	// Encrypt encrypts a clear price and a given seed.
+	Encrypt(seed string, price float64) (string, error)
+}
+
+// Decrypter decrypts prices. Bidders receiving encrypted prices only need
+// a Decrypter, which can't mint valid ones.
+type Decrypter interface {
+	// Decrypt decrypts an encrypted price.
+	Decrypt(encryptedPrice string) (float64, error)
+}
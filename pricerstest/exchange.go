@@ -0,0 +1,64 @@
+package pricerstest
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+// Clock returns the current time.
+type Clock = doubleclick.Clock
+
+// FixedClock : Returns a Clock always returning t.
+func FixedClock(t time.Time) Clock {
+	return func() time.Time {
+		return t
+	}
+}
+
+// StepClock : Returns a Clock starting at start and moving forward by step on every call.
+func StepClock(start time.Time, step time.Duration) Clock {
+	var mu sync.Mutex
+	next := start
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now := next
+		next = next.Add(step)
+		return now
+	}
+}
+
+// Exchange simulates an exchange issuing DoubleClick encrypted prices,
+// with Google-style IVs made of the clock time followed by random bytes.
+// Given the same keys, clock and seed, it issues the same tokens, in order.
+// An Exchange is safe for concurrent use.
+type Exchange struct {
+	mu     sync.Mutex
+	pricer *doubleclick.DoubleClickPricer
+}
+
+// NewExchange returns an Exchange encrypting prices with the given keys,
+// as NewDoubleClickPricer does, taking time from clock and IV random bytes
+// from a pseudo random source initialized with seed.
+func NewExchange(encryptionKey string, integrityKey string, isBase64Keys bool, keyDecodingMode helpers.KeyDecodingMode, scaleFactor float64, clock Clock, seed int64) (*Exchange, error) {
+	pricer, err := doubleclick.NewDoubleClickPricer(encryptionKey, integrityKey, isBase64Keys, keyDecodingMode, scaleFactor, false,
+		doubleclick.WithClock(clock),
+		doubleclick.WithRand(rand.New(rand.NewSource(seed))))
+	if err != nil {
+		return nil, err
+	}
+	return &Exchange{pricer: pricer}, nil
+}
+
+// Clear : Returns the encrypted price the exchange would substitute to the
+// price macro of an auction cleared at price.
+func (e *Exchange) Clear(price float64) (string, error) {
+	// The pseudo random source is not safe for concurrent use.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pricer.EncryptNow(price)
+}
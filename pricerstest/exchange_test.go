@@ -0,0 +1,73 @@
+package pricerstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+)
+
+const (
+	encryptionKey = "652f83ada0545157a1b7fb0c0e09f59e7337332fe7abd4eb10449b8ee6c39135"
+	integrityKey  = "bd0a3dfb82ad95c5e63e159a62f73c6aca98ba2495322194759d512d77eb2bb5"
+)
+
+func buildNewExchange(t *testing.T, clock Clock) *Exchange {
+	exchange, err := NewExchange(encryptionKey, integrityKey, false, helpers.Hexa, 1000000, clock, 42)
+	assert.Nil(t, err, "Error creating new Exchange : ", err)
+	return exchange
+}
+
+func TestExchangeIssuesValidPrices(t *testing.T) {
+	// Setup:
+	now := time.Unix(1700000000, 0)
+	exchange := buildNewExchange(t, StepClock(now, time.Second))
+	pricer, err := doubleclick.NewDoubleClickPricer(encryptionKey, integrityKey, false, helpers.Hexa, 1000000, false)
+	assert.NoError(t, err)
+
+	for _, clear := range []float64{1.354, 3.24, 0.01} {
+		// Execute:
+		encrypted, err := exchange.Clear(clear)
+		price, at, decryptErr := pricer.DecryptWithTime(encrypted)
+
+		// Verify:
+		assert.NoError(t, err)
+		assert.NoError(t, decryptErr)
+		assert.InDelta(t, clear, price, 0.000001)
+		assert.True(t, now.Equal(at), "Time should be %s but was %s", now, at)
+		now = now.Add(time.Second)
+	}
+}
+
+func TestExchangeIsDeterministic(t *testing.T) {
+	// Setup:
+	start := time.Unix(1700000000, 0)
+	first := buildNewExchange(t, StepClock(start, time.Second))
+	second := buildNewExchange(t, StepClock(start, time.Second))
+
+	for i := 0; i < 3; i++ {
+		// Execute:
+		a, errA := first.Clear(1.354)
+		b, errB := second.Clear(1.354)
+
+		// Verify:
+		assert.NoError(t, errA)
+		assert.NoError(t, errB)
+		assert.Equal(t, a, b)
+	}
+}
+
+func TestExchangeIssuesDistinctPricesUnderFixedClock(t *testing.T) {
+	// Setup:
+	exchange := buildNewExchange(t, FixedClock(time.Unix(1700000000, 0)))
+
+	// Execute:
+	a, _ := exchange.Clear(1.354)
+	b, _ := exchange.Clear(1.354)
+
+	// Verify:
+	assert.NotEqual(t, a, b)
+}
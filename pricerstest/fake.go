@@ -0,0 +1,173 @@
+// Package pricerstest provides utilities for testing code depending on pricers,
+// without embedding real keys.
+package pricerstest
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benjaminch/pricers/metrics"
+)
+
+// ErrUnknownPrice is returned when decrypting a price that was neither
+// scripted nor encrypted by a Fake.
+var ErrUnknownPrice = errors.New("Encrypted price was not scripted")
+
+// fakePrefix prefixes the encrypted prices returned by Fake.Encrypt.
+const fakePrefix = "fake:"
+
+// Call records a call made to a Fake.
+type Call struct {
+	Operation metrics.Operation
+	// Seed is the seed given to Encrypt.
+	Seed string
+	// Price is the price given to Encrypt, or returned by Decrypt.
+	Price float64
+	// EncryptedPrice is the price returned by Encrypt, or given to Decrypt.
+	EncryptedPrice string
+	Err            error
+}
+
+type result struct {
+	price float64
+	err   error
+}
+
+// Fake is a pricers.Pricer recording its calls and returning scripted results.
+//
+// Unless scripted, Encrypt returns a readable "fake:<seed>:<price>" token
+// that Decrypt gives the price back from, so that round trips work without keys.
+// A Fake is safe for concurrent use.
+type Fake struct {
+	latency time.Duration
+
+	mu         sync.Mutex
+	calls      []Call
+	prices     map[string]result
+	encryptErr error
+	decryptErr error
+}
+
+// Option configures a Fake.
+type Option func(*Fake)
+
+// WithLatency makes every Encrypt and Decrypt call sleep for latency.
+func WithLatency(latency time.Duration) Option {
+	return func(f *Fake) {
+		f.latency = latency
+	}
+}
+
+// NewFake returns a Fake with no scripted result.
+func NewFake(options ...Option) *Fake {
+	f := &Fake{prices: map[string]result{}}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// SetPrice : Scripts the price Decrypt returns for an encrypted price.
+func (f *Fake) SetPrice(encryptedPrice string, price float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prices[encryptedPrice] = result{price: price}
+}
+
+// SetError : Scripts the error Decrypt returns for an encrypted price.
+func (f *Fake) SetError(encryptedPrice string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prices[encryptedPrice] = result{err: err}
+}
+
+// FailEncrypt : Makes every Encrypt call fail with err, nil restoring the default behavior.
+func (f *Fake) FailEncrypt(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.encryptErr = err
+}
+
+// FailDecrypt : Makes every Decrypt call of a price not scripted fail with err,
+// nil restoring the default behavior.
+func (f *Fake) FailDecrypt(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.decryptErr = err
+}
+
+// Calls : Returns the calls made so far, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Reset : Forgets the calls and scripted results.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.prices = map[string]result{}
+	f.encryptErr = nil
+	f.decryptErr = nil
+}
+
+// Encrypt returns a "fake:<seed>:<price>" token, or the error set by FailEncrypt.
+func (f *Fake) Encrypt(seed string, price float64) (string, error) {
+	f.sleep()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := Call{Operation: metrics.Encrypt, Seed: seed, Price: price, Err: f.encryptErr}
+	if call.Err == nil {
+		call.EncryptedPrice = fakePrefix + seed + ":" + strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	f.calls = append(f.calls, call)
+	return call.EncryptedPrice, call.Err
+}
+
+// Decrypt returns the scripted result of encryptedPrice if any, the error set
+// by FailDecrypt if any, or the price of a token returned by Encrypt.
+// Other prices fail with ErrUnknownPrice.
+func (f *Fake) Decrypt(encryptedPrice string) (float64, error) {
+	f.sleep()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r, ok := f.prices[encryptedPrice]
+	switch {
+	case ok:
+	case f.decryptErr != nil:
+		r.err = f.decryptErr
+	default:
+		r = parseFake(encryptedPrice)
+	}
+
+	f.calls = append(f.calls, Call{Operation: metrics.Decrypt, Price: r.price, EncryptedPrice: encryptedPrice, Err: r.err})
+	return r.price, r.err
+}
+
+func (f *Fake) sleep() {
+	if f.latency > 0 {
+		time.Sleep(f.latency)
+	}
+}
+
+// parseFake returns the price of a token returned by Encrypt.
+func parseFake(encryptedPrice string) result {
+	if !strings.HasPrefix(encryptedPrice, fakePrefix) {
+		return result{err: ErrUnknownPrice}
+	}
+	i := strings.LastIndexByte(encryptedPrice, ':')
+	price, err := strconv.ParseFloat(encryptedPrice[i+1:], 64)
+	if err != nil {
+		return result{err: ErrUnknownPrice}
+	}
+	return result{price: price}
+}
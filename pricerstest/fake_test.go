@@ -0,0 +1,103 @@
+package pricerstest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/metrics"
+)
+
+var _ pricers.Pricer = (*Fake)(nil)
+
+func TestFakeRoundTrip(t *testing.T) {
+	// Setup:
+	fake := NewFake()
+
+	// Execute:
+	encrypted, encryptErr := fake.Encrypt("seed:1", 1.354)
+	price, decryptErr := fake.Decrypt(encrypted)
+
+	// Verify:
+	assert.NoError(t, encryptErr)
+	assert.NoError(t, decryptErr)
+	assert.Equal(t, "fake:seed:1:1.354", encrypted)
+	assert.Equal(t, 1.354, price)
+	assert.Equal(t, []Call{
+		{Operation: metrics.Encrypt, Seed: "seed:1", Price: 1.354, EncryptedPrice: encrypted},
+		{Operation: metrics.Decrypt, Price: 1.354, EncryptedPrice: encrypted},
+	}, fake.Calls())
+}
+
+func TestFakeScriptedResults(t *testing.T) {
+	// Setup:
+	errScripted := errors.New("scripted")
+	fake := NewFake()
+	fake.SetPrice("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg", 1.354)
+	fake.SetError("ce131TRp7waIZI2qOiRr2DMm2sSIeGh_wIAwVQ", errScripted)
+
+	// Execute:
+	price, err := fake.Decrypt("anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+	_, scriptedErr := fake.Decrypt("ce131TRp7waIZI2qOiRr2DMm2sSIeGh_wIAwVQ")
+	_, unknownErr := fake.Decrypt("K6tfPnPvN_5E2xS3GssrFYeouJJRkBQqxR_FxQ")
+
+	// Verify:
+	assert.NoError(t, err)
+	assert.Equal(t, 1.354, price)
+	assert.ErrorIs(t, scriptedErr, errScripted)
+	assert.ErrorIs(t, unknownErr, ErrUnknownPrice)
+	assert.Len(t, fake.Calls(), 3)
+}
+
+func TestFakeFailures(t *testing.T) {
+	// Setup:
+	errFailed := errors.New("failed")
+	fake := NewFake()
+	fake.SetPrice("scripted", 1)
+	fake.FailEncrypt(errFailed)
+	fake.FailDecrypt(errFailed)
+
+	// Execute:
+	_, encryptErr := fake.Encrypt("", 1)
+	_, decryptErr := fake.Decrypt("fake::1")
+	price, scriptedErr := fake.Decrypt("scripted")
+
+	// Verify:
+	assert.ErrorIs(t, encryptErr, errFailed)
+	assert.ErrorIs(t, decryptErr, errFailed)
+	assert.NoError(t, scriptedErr)
+	assert.Equal(t, 1.0, price)
+}
+
+func TestFakeReset(t *testing.T) {
+	// Setup:
+	fake := NewFake()
+	fake.SetPrice("scripted", 1)
+	fake.FailEncrypt(errors.New("failed"))
+	_, _ = fake.Decrypt("scripted")
+
+	// Execute:
+	fake.Reset()
+	_, encryptErr := fake.Encrypt("", 1)
+	_, decryptErr := fake.Decrypt("scripted")
+
+	// Verify:
+	assert.NoError(t, encryptErr)
+	assert.ErrorIs(t, decryptErr, ErrUnknownPrice)
+	assert.Len(t, fake.Calls(), 2)
+}
+
+func TestFakeLatency(t *testing.T) {
+	// Setup:
+	fake := NewFake(WithLatency(20 * time.Millisecond))
+	start := time.Now()
+
+	// Execute:
+	_, _ = fake.Decrypt("fake::1")
+
+	// Verify:
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
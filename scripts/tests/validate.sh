@@ -15,23 +15,33 @@ GOGLOB="${GOGLOB/ bin/}"
 GOGLOB="${GOGLOB/ docs/}"
 GOGLOB="${GOGLOB/ vendor/}"
 
-# Check that there are no formatting issues
-if $AUTOFMT; then
-  COMMAND="go fmt ./..."
-  echo "Running: $COMMAND"
-  `$COMMAND`
-fi
-
-if $VET; then
-  # Fix for the go 1.10 vet bug (https://github.com/w0rp/ale/issues/1358)
-  COMMAND="go vet ./..."
-  echo "Running: $COMMAND"
-  `$COMMAND`
-fi
-
-# Tests
-echo "Running: Tests"
-go test -race ./... -cover -covermode=atomic -coverprofile=coverage.out
+# Nested modules, keeping their dependencies out of the core module.
+MODULES=". instrumentation/otelpricers metrics/prometheus"
+
+for MODULE in $MODULES; do
+  echo "Module: $MODULE"
+  pushd "$MODULE" > /dev/null
+
+  # Check that there are no formatting issues
+  if $AUTOFMT; then
+    COMMAND="go fmt ./..."
+    echo "Running: $COMMAND"
+    `$COMMAND`
+  fi
+
+  if $VET; then
+    # Fix for the go 1.10 vet bug (https://github.com/w0rp/ale/issues/1358)
+    COMMAND="go vet ./..."
+    echo "Running: $COMMAND"
+    `$COMMAND`
+  fi
+
+  # Tests
+  echo "Running: Tests"
+  go test -race ./... -cover -covermode=atomic -coverprofile=coverage.out
+
+  popd > /dev/null
+done
 
 # Report card generates a report on the quality of an open source go project.
 if $REPORTCARD; then
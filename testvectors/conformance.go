@@ -0,0 +1,53 @@
+package testvectors
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/benjaminch/pricers"
+)
+
+// Factory builds the pricer a vector should be checked against.
+type Factory func(v Vector) (pricers.Pricer, error)
+
+// Run checks a pricer implementation against every vector of a protocol,
+// each vector being run as a subtest named after it.
+// Decrypting must give back the clear price, within one unit of the scale
+// factor. Encrypting with the vector seed, when known, must give back the
+// encrypted price, padding aside.
+func Run(t *testing.T, protocol string, factory Factory) {
+	t.Helper()
+
+	vectors := ForProtocol(protocol)
+	if len(vectors) == 0 {
+		t.Fatalf("no test vectors for protocol %q", protocol)
+	}
+
+	for _, v := range vectors {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			pricer, err := factory(v)
+			if err != nil {
+				t.Fatalf("cannot build pricer: %s", err)
+			}
+
+			price, err := pricer.Decrypt(v.Encrypted)
+			if err != nil {
+				t.Errorf("Decrypt(%q) failed: %s", v.Encrypted, err)
+			} else if math.Abs(price-v.Clear) > 1/v.ScaleFactor {
+				t.Errorf("Decrypt(%q) = %v, should be %v", v.Encrypted, price, v.Clear)
+			}
+
+			if !v.CanEncrypt() {
+				return
+			}
+			encrypted, err := pricer.Encrypt(*v.Seed, v.Clear)
+			if err != nil {
+				t.Errorf("Encrypt(%q, %v) failed: %s", *v.Seed, v.Clear, err)
+			} else if strings.TrimRight(encrypted, "=") != strings.TrimRight(v.Encrypted, "=") {
+				t.Errorf("Encrypt(%q, %v) = %q, should be %q", *v.Seed, v.Clear, encrypted, v.Encrypted)
+			}
+		})
+	}
+}
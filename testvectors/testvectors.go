@@ -0,0 +1,63 @@
+// Package testvectors provides machine-readable price encryption test
+// vectors for every supported protocol, along with a conformance runner
+// any pricer implementation can run in its own tests.
+//
+// Vectors are embedded from vectors.json so that they can be shared with
+// implementations written in other languages.
+package testvectors
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"github.com/benjaminch/pricers/helpers"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+// Vector describes a price encrypted with a given set of keys.
+type Vector struct {
+	// Name identifies the vector, it is unique.
+	Name string `json:"name"`
+	// Protocol is the price encryption protocol, e.g. "doubleclick".
+	Protocol        string                  `json:"protocol"`
+	EncryptionKey   string                  `json:"encryption_key"`
+	IntegrityKey    string                  `json:"integrity_key"`
+	KeyDecodingMode helpers.KeyDecodingMode `json:"key_decoding_mode"`
+	Base64Keys      bool                    `json:"base64_keys"`
+	ScaleFactor     float64                 `json:"scale_factor"`
+	// Seed is the seed the price was encrypted with.
+	// It is nil when unknown, the vector being usable for decryption only.
+	Seed *string `json:"seed,omitempty"`
+	// IV is the hex encoded initialization vector carried by the encrypted price.
+	IV string `json:"iv"`
+	// Clear is the price in currency units.
+	Clear     float64 `json:"clear"`
+	Encrypted string  `json:"encrypted"`
+}
+
+// CanEncrypt : Returns true if the vector seed is known.
+func (v Vector) CanEncrypt() bool {
+	return v.Seed != nil
+}
+
+// All : Returns every vector.
+func All() []Vector {
+	var vectors []Vector
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		panic("testvectors: invalid vectors.json: " + err.Error())
+	}
+	return vectors
+}
+
+// ForProtocol : Returns the vectors of a protocol.
+func ForProtocol(protocol string) []Vector {
+	var vectors []Vector
+	for _, v := range All() {
+		if v.Protocol == protocol {
+			vectors = append(vectors, v)
+		}
+	}
+	return vectors
+}
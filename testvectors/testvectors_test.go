@@ -0,0 +1,42 @@
+package testvectors
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVectorsAreConsistent(t *testing.T) {
+	// Setup:
+	names := map[string]bool{}
+
+	for _, v := range All() {
+		// Execute:
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v.Encrypted, "="))
+
+		// Verify:
+		assert.False(t, names[v.Name], "Vector name %q is not unique", v.Name)
+		names[v.Name] = true
+		assert.NotEmpty(t, v.Protocol, "Vector %q has no protocol", v.Name)
+		assert.Positive(t, v.ScaleFactor, "Vector %q has no scale factor", v.Name)
+		if assert.NoError(t, err, "Vector %q encrypted price is not base64", v.Name) && assert.GreaterOrEqual(t, len(decoded), 16) {
+			assert.Equal(t, v.IV, hex.EncodeToString(decoded[:16]), "Vector %q IV doesn't match its encrypted price", v.Name)
+		}
+	}
+}
+
+func TestForProtocol(t *testing.T) {
+	// Execute:
+	vectors := ForProtocol("doubleclick")
+	unknown := ForProtocol("unknown")
+
+	// Verify:
+	assert.NotEmpty(t, vectors)
+	assert.Empty(t, unknown)
+	for _, v := range vectors {
+		assert.Equal(t, "doubleclick", v.Protocol)
+	}
+}
@@ -0,0 +1,188 @@
+package winnotice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/benjaminch/pricers"
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/logging"
+	"github.com/benjaminch/pricers/openrtb"
+)
+
+// ErrMissingPrice is returned when the request carries no encrypted price.
+var ErrMissingPrice = errors.New("Win notice has no price")
+
+// Policy : Describing how invalid encrypted prices should be handled.
+type Policy int
+
+const (
+	// Reject : Requests with an invalid price are answered with an error status.
+	Reject Policy = iota
+	// Flag : Requests with an invalid price are passed to the next handler,
+	// the error being reported in the context Result.
+	Flag
+)
+
+// Outcome : Describing the outcome of a price decryption.
+type Outcome int
+
+const (
+	// OutcomeOK : Price was decrypted.
+	OutcomeOK Outcome = iota
+	// OutcomeMissing : Price parameter is missing or wasn't substituted.
+	OutcomeMissing
+	// OutcomeMalformed : Encrypted price has a wrong size or encoding.
+	OutcomeMalformed
+	// OutcomeWrongSignature : Encrypted price signature doesn't match.
+	OutcomeWrongSignature
+)
+
+// String : Returns the Outcome string representation.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeOK:
+		return "ok"
+	case OutcomeMissing:
+		return "missing"
+	case OutcomeMalformed:
+		return "malformed"
+	case OutcomeWrongSignature:
+		return "wrong_signature"
+	}
+	return "unknown"
+}
+
+// StatusCode : Returns the HTTP status code a rejected request is answered with.
+func (o Outcome) StatusCode() int {
+	switch o {
+	case OutcomeOK:
+		return http.StatusOK
+	case OutcomeWrongSignature:
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// Result holds the decrypted price stashed in the request context.
+type Result struct {
+	EncryptedPrice string
+	Price          float64
+	Outcome        Outcome
+	Err            error
+}
+
+// Valid : Tells whether the price was successfully decrypted.
+func (r Result) Valid() bool {
+	return r.Outcome == OutcomeOK
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying result.
+func NewContext(ctx context.Context, result Result) context.Context {
+	return context.WithValue(ctx, contextKey{}, result)
+}
+
+// FromContext returns the Result stored in ctx, if any.
+func FromContext(ctx context.Context) (Result, bool) {
+	result, ok := ctx.Value(contextKey{}).(Result)
+	return result, ok
+}
+
+// Stats holds the outcome counters of a Middleware.
+type Stats struct {
+	OK             uint64
+	Missing        uint64
+	Malformed      uint64
+	WrongSignature uint64
+}
+
+// Middleware decrypts the clearing price of win notices.
+type Middleware struct {
+	pricer pricers.Decrypter
+	param  string
+	policy Policy
+	logger logging.Logger
+	counts [4]uint64
+}
+
+// Option configures a Middleware.
+type Option func(*Middleware)
+
+// WithLogger makes the middleware report rejected requests to logger,
+// with the outcome and the error they are rejected for.
+func WithLogger(logger logging.Logger) Option {
+	return func(m *Middleware) {
+		m.logger = logger
+	}
+}
+
+// NewMiddleware returns a Middleware decrypting with pricer the price
+// carried by the param query parameter, e.g. a doubleclick.DecryptOnlyPricer.
+func NewMiddleware(pricer pricers.Decrypter, param string, policy Policy, options ...Option) *Middleware {
+	m := &Middleware{pricer: pricer, param: param, policy: policy}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// Handler wraps next, stashing a Result in the request context.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := m.decrypt(r)
+		atomic.AddUint64(&m.counts[result.Outcome], 1)
+
+		if !result.Valid() && m.policy == Reject {
+			// Errors may quote the encrypted price, only the status is answered.
+			status := result.Outcome.StatusCode()
+			if m.logger != nil {
+				m.logger.Debug("winnotice: request rejected",
+					logging.F("outcome", result.Outcome.String()),
+					logging.F("status", status),
+					logging.F("error", result.Err.Error()))
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), result)))
+	})
+}
+
+// Stats returns a snapshot of the outcome counters.
+func (m *Middleware) Stats() Stats {
+	return Stats{
+		OK:             atomic.LoadUint64(&m.counts[OutcomeOK]),
+		Missing:        atomic.LoadUint64(&m.counts[OutcomeMissing]),
+		Malformed:      atomic.LoadUint64(&m.counts[OutcomeMalformed]),
+		WrongSignature: atomic.LoadUint64(&m.counts[OutcomeWrongSignature]),
+	}
+}
+
+func (m *Middleware) decrypt(r *http.Request) Result {
+	encryptedPrice, err := openrtb.ExtractPrice(r.URL.String(), m.param, false)
+	switch {
+	case errors.Is(err, openrtb.ErrMissingPrice), errors.Is(err, openrtb.ErrUnexpandedMacro):
+		return Result{Outcome: OutcomeMissing, Err: fmt.Errorf("%w: %w", ErrMissingPrice, err)}
+	case err != nil:
+		return Result{Outcome: OutcomeMalformed, Err: err}
+	}
+
+	price, err := m.pricer.Decrypt(encryptedPrice)
+	result := Result{EncryptedPrice: encryptedPrice, Price: price, Err: err}
+	switch {
+	case err == nil:
+		result.Outcome = OutcomeOK
+	case errors.Is(err, doubleclick.ErrWrongSignature):
+		result.Outcome = OutcomeWrongSignature
+	default:
+		result.Outcome = OutcomeMalformed
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+package winnotice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/benjaminch/pricers/doubleclick"
+	"github.com/benjaminch/pricers/helpers"
+	"github.com/benjaminch/pricers/logging"
+	"github.com/benjaminch/pricers/openrtb"
+)
+
+func buildNewMiddleware(t *testing.T, policy Policy) *Middleware {
+	// Win notices only need decryption.
+	pricer, err := doubleclick.NewDecryptOnlyPricer(
+		"ZS-DraBUUVeht_sMDgn1nnM3My_nq9TrEESbjubDkTU",
+		"vQo9-4KtlcXmPhWaYvc8asqYuiSVMiGUdZ1RLXfrK7U",
+		true, // Keys are base64
+		helpers.Utf8,
+		1000000,
+		false,
+	)
+	assert.Nil(t, err, "Error creating new Pricer : ", err)
+	return NewMiddleware(pricer, "price", policy)
+}
+
+// serve runs a request through the middleware, returning the response
+// and the Result seen by the next handler, if it was called.
+func serve(m *Middleware, target string) (*httptest.ResponseRecorder, *Result) {
+	var seen *Result
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, ok := FromContext(r.Context())
+		if ok {
+			seen = &result
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder, seen
+}
+
+func TestMiddlewareDecryptsPrice(t *testing.T) {
+	// Setup:
+	m := buildNewMiddleware(t, Reject)
+
+	// Execute:
+	recorder, result := serve(m, "/win?price=anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpg")
+
+	// Verify:
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.NotNil(t, result)
+	assert.True(t, result.Valid())
+	assert.InDelta(t, 1.354, result.Price, 0.001)
+	assert.Equal(t, Stats{OK: 1}, m.Stats())
+}
+
+func TestMiddlewareRejectsInvalidPrices(t *testing.T) {
+	// Setup:
+	m := buildNewMiddleware(t, Reject)
+	var testCases = []struct {
+		target string
+		status int
+	}{
+		{"/win", http.StatusBadRequest},
+		{"/win?price=${AUCTION_PRICE}", http.StatusBadRequest},
+		{"/win?price=tooshort", http.StatusBadRequest},
+		{"/win?price=anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA", http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		recorder, result := serve(m, testCase.target)
+
+		// Verify:
+		assert.Equal(t, testCase.status, recorder.Code, testCase.target)
+		assert.Nil(t, result, testCase.target)
+	}
+	assert.Equal(t, Stats{Missing: 2, Malformed: 1, WrongSignature: 1}, m.Stats())
+}
+
+// recordingLogger is a logging.Logger keeping the messages it receives.
+type recordingLogger struct {
+	messages []string
+	fields   [][]logging.Field
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...logging.Field) {
+	l.messages = append(l.messages, msg)
+	l.fields = append(l.fields, fields)
+}
+
+func TestMiddlewareRejectionDoesNotLeakErrors(t *testing.T) {
+	// Setup:
+	token := "anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA"
+	logger := &recordingLogger{}
+	m := buildNewMiddleware(t, Reject)
+	WithLogger(logger)(m)
+
+	// Execute:
+	recorder, _ := serve(m, "/win?price="+token)
+
+	// Verify:
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, http.StatusText(http.StatusForbidden)+"\n", recorder.Body.String())
+	// Decryption errors quote the first chars of the encrypted price.
+	assert.NotContains(t, recorder.Body.String(), token[:6])
+	assert.Equal(t, []string{"winnotice: request rejected"}, logger.messages)
+	assert.Contains(t, logger.fields[0], logging.F("outcome", "wrong_signature"))
+	assert.Contains(t, logger.fields[0], logging.F("status", http.StatusForbidden))
+}
+
+func TestMiddlewareFlagsInvalidPrices(t *testing.T) {
+	// Setup:
+	m := buildNewMiddleware(t, Flag)
+
+	// Execute:
+	recorder, result := serve(m, "/win?price=anCGGFJApcfB6ZGc6mindhpTrYXHY4ONo7lXpA")
+
+	// Verify:
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.NotNil(t, result)
+	assert.False(t, result.Valid())
+	assert.Equal(t, OutcomeWrongSignature, result.Outcome)
+	assert.ErrorIs(t, result.Err, doubleclick.ErrWrongSignature)
+	assert.Equal(t, Stats{WrongSignature: 1}, m.Stats())
+}
+
+func TestMiddlewareReportsMissingPrices(t *testing.T) {
+	// Setup:
+	m := buildNewMiddleware(t, Flag)
+	var testCases = []struct {
+		target string
+		err    error
+	}{
+		{"/win", openrtb.ErrMissingPrice},
+		{"/win?price=${AUCTION_PRICE}", openrtb.ErrUnexpandedMacro},
+	}
+
+	for _, testCase := range testCases {
+		// Execute:
+		_, result := serve(m, testCase.target)
+
+		// Verify:
+		assert.NotNil(t, result)
+		assert.Equal(t, OutcomeMissing, result.Outcome, testCase.target)
+		assert.ErrorIs(t, result.Err, ErrMissingPrice)
+		assert.ErrorIs(t, result.Err, testCase.err)
+	}
+	assert.Equal(t, Stats{Missing: 2}, m.Stats())
+}